@@ -1,7 +1,11 @@
 package cmd
 
 import (
+	"os/exec"
+	"strings"
+
 	"github.com/amido/mrbuild/internal/affected"
+	"github.com/amido/mrbuild/internal/config"
 	"github.com/amido/mrbuild/internal/util"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -29,21 +33,88 @@ func init() {
 	// - path to data file containing sample data
 	var datafile string
 
+	// - format of the data in the datafile or from the pipe
+	var inputFormat string
+
 	// - list of projects to ignore
 	var ignore string
 
+	// - select the affected projects to build by their tags or name
+	var onlyTags string
+	var skipTags string
+	var only string
+
+	// - do not read directives from the commit message or pull request labels
+	var noDirectives bool
+
+	// - how the changes should be compared against the branch
+	var baseMode string
+
+	// - changes in the working tree that should be included
+	var includeWorktree bool
+	var stagedOnly bool
+	var includeUntracked bool
+
+	// - refs for comparing a range of commits
+	var from string
+	var to string
+	var since string
+
+	// - how the git repository should be read
+	var gitProvider string
+
+	// - include changes from within updated submodules
+	var recurseSubmodules bool
+
+	// - how to handle shallow clones
+	var deepen int
+	var deepenMax int
+	var shallowFallback string
+
+	// - record and compare against the last successful build of each project
+	var lastSuccessMarker string
+
+	// - detect changes by comparing with a snapshot of the file hashes
+	var useSnapshot bool
+	var snapshotManifest string
+
 	// add the command
 	rootCmd.AddCommand(affectedCmd)
 
 	affectedCmd.Flags().StringVarP(&cfgFile, "config", "c", "./mrbuild.yaml", "Path to the configuration file for the repository")
 	affectedCmd.Flags().StringVar(&ignore, "ignore", "", "List of projects that should not be processed (command delimited).")
+	affectedCmd.Flags().StringVar(&onlyTags, "only-tags", "", "Only build the affected projects that have one of the tags (comma delimited)")
+	affectedCmd.Flags().StringVar(&skipTags, "skip-tags", "", "Do not build the affected projects that have any of the tags (comma delimited)")
+	affectedCmd.Flags().StringVar(&only, "only", "", "Only build the affected projects with a name that matches the regular expression")
+	affectedCmd.Flags().BoolVar(&noDirectives, "no-directives", false, "Do not read directives, such as [skip mrbuild], from the commit message or pull request labels")
 	affectedCmd.Flags().StringVar(&datafile, "datafile", "", "Path to file containing git file data to work with")
+	affectedCmd.Flags().StringVar(&inputFormat, "input-format", "lines", "Format of the datafile or piped data, lines, nul, porcelain, porcelain-v2, json, github or gitlab")
 	affectedCmd.Flags().IntVar(&workers, "workers", 1, "Number of workers to spawn jobs to")
-
-	viper.BindPFlag("config", affectedCmd.Flags().Lookup("config"))
-	viper.BindPFlag("options.ignore", affectedCmd.Flags().Lookup("ignore"))
-	viper.BindPFlag("datafile", affectedCmd.Flags().Lookup("datafile"))
+	affectedCmd.Flags().StringVar(&baseMode, "base-mode", "merge-base", "How changes are compared against the branch, merge-base, tip or range")
+	affectedCmd.Flags().BoolVar(&includeWorktree, "include-worktree", false, "Include staged and unstaged changes in the working tree, only applies to range mode as the other modes already include them")
+	affectedCmd.Flags().BoolVar(&stagedOnly, "staged-only", false, "Only include the changes in the working tree that have been staged")
+	affectedCmd.Flags().BoolVar(&includeUntracked, "include-untracked", false, "Include files that are not tracked by git")
+	affectedCmd.Flags().StringVar(&from, "from", "", "Ref at the start of the range of commits to compare, implies range mode")
+	affectedCmd.Flags().StringVar(&to, "to", "", "Ref at the end of the range of commits to compare, implies range mode")
+	affectedCmd.Flags().StringVar(&since, "since", "", "Ref to compare HEAD against, implies range mode")
+	affectedCmd.Flags().BoolVar(&recurseSubmodules, "recurse-submodules", false, "Include the files that have changed within updated submodules")
+	affectedCmd.Flags().IntVar(&deepen, "deepen", 0, "Number of commits to deepen a shallow clone by, at each step, until the commits to compare are found")
+	affectedCmd.Flags().IntVar(&deepenMax, "deepen-max", 0, "Maximum number of commits to deepen a shallow clone by")
+	affectedCmd.Flags().StringVar(&shallowFallback, "shallow-fallback", "fail", "What to do if the changes cannot be found in a shallow clone, fail or all")
+	affectedCmd.Flags().BoolVar(&useSnapshot, "snapshot", false, "Detect changes by comparing the hashes of the files in each project with the snapshot manifest, instead of using git")
+	affectedCmd.Flags().StringVar(&snapshotManifest, "snapshot-manifest", "", "Path to the snapshot manifest file (default \".mrbuild-snapshot.json\")")
+	affectedCmd.Flags().StringVar(&lastSuccessMarker, "last-success-marker", "none", "Record the last successful build of each project, and compare against it, using none, tag or notes")
+	affectedCmd.Flags().StringVar(&gitProvider, "git-provider", "exec", "How the git repository is read, exec to run git or native to read it in-process")
+
+	// the flags that are shared with the explain and snapshot commands are bound in initConfig
 	viper.BindPFlag("workers", affectedCmd.Flags().Lookup("workers"))
+	viper.BindPFlag("compare.worktree", affectedCmd.Flags().Lookup("include-worktree"))
+	viper.BindPFlag("compare.staged", affectedCmd.Flags().Lookup("staged-only"))
+	viper.BindPFlag("compare.untracked", affectedCmd.Flags().Lookup("include-untracked"))
+	viper.BindPFlag("compare.submodules", affectedCmd.Flags().Lookup("recurse-submodules"))
+	viper.BindPFlag("shallow.deepen", affectedCmd.Flags().Lookup("deepen"))
+	viper.BindPFlag("shallow.max", affectedCmd.Flags().Lookup("deepen-max"))
+	viper.BindPFlag("shallow.fallback", affectedCmd.Flags().Lookup("shallow-fallback"))
 
 }
 
@@ -53,6 +124,13 @@ func affectedPreRun(ccmd *cobra.Command, args []string) {
 	if Config.Input.Datafile != "" && !util.Exists(Config.Input.Datafile) {
 		App.Logger.Fatalf("Specified data file cannot be found: %s", Config.Input.Datafile)
 	}
+
+	// if git is going to be run to get the changes, check that it can be found
+	if Config.Input.Datafile == "" && !util.IsInputFromPipe() && !Config.Input.Snapshot.Enabled && !strings.EqualFold(Config.Input.Compare.Provider, config.GitProviderNative) {
+		if _, err := exec.LookPath("git"); err != nil {
+			App.Logger.Fatalf("Unable to find git in the PATH, use '--git-provider %s' to read the repository without it", config.GitProviderNative)
+		}
+	}
 }
 
 func executeAffectedRun(ccmd *cobra.Command, args []string) {
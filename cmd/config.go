@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/amido/mrbuild/internal/discovery"
+	"github.com/amido/mrbuild/internal/util"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	configCmd = &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the configuration of the repository",
+		Long:  "",
+	}
+
+	configDumpCmd = &cobra.Command{
+		Use:   "dump",
+		Short: "Show the configuration, with the defaults and templates applied to each project, as YAML",
+		Long:  "",
+		Run:   executeConfigDumpRun,
+	}
+)
+
+func init() {
+
+	// add the commands
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configDumpCmd)
+
+	configDumpCmd.Flags().StringVarP(&cfgFile, "config", "c", "./mrbuild.yaml", "Path to the configuration file for the repository")
+
+	// the config flag is bound in initConfig as it is shared with other commands
+}
+
+func executeConfigDumpRun(ccmd *cobra.Command, args []string) {
+
+	// check the runtime configuration, which applies the defaults and templates
+	err := Config.Check()
+	if err != nil {
+		App.Logger.Fatalln(err.Error())
+	}
+
+	err = discovery.Apply(&Config, App.Logger)
+	if err != nil {
+		App.Logger.Fatalf("Unable to discover projects: %s", err.Error())
+	}
+
+	settings := util.ToMap(Config.Input).(map[string]interface{})
+
+	// the version is of the application rather than the configuration
+	delete(settings, "version")
+
+	var data bytes.Buffer
+
+	encoder := yaml.NewEncoder(&data)
+	encoder.SetIndent(2)
+
+	err = encoder.Encode(settings)
+	if err != nil {
+		App.Logger.Fatalf("Unable to write configuration: %s", err.Error())
+	}
+
+	fmt.Print(data.String())
+}
@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/amido/mrbuild/internal/affected"
+	"github.com/amido/mrbuild/internal/config"
+	"github.com/amido/mrbuild/internal/discovery"
+	"github.com/spf13/cobra"
+)
+
+var (
+	explainCmd = &cobra.Command{
+		Use:   "explain [path...]",
+		Short: "Explain which projects each file belongs to, or why a project was affected",
+		Long:  "",
+		Run:   executeExplainRun,
+
+		// Execute prerun function to ensure that the changes can be read for a project
+		PreRun: explainPreRun,
+	}
+
+	// name of the project to explain the selection of
+	explainProject string
+)
+
+func init() {
+
+	// declare command variables
+
+	// - path to data file containing sample data
+	var datafile string
+
+	// - format of the data in the datafile or from the pipe
+	var inputFormat string
+
+	// - list of projects to ignore
+	var ignore string
+
+	// - select the affected projects to build by their tags or name
+	var onlyTags string
+	var skipTags string
+	var only string
+
+	// - do not read directives from the commit message or pull request labels
+	var noDirectives bool
+
+	// - how the changes should be compared against the branch
+	var baseMode string
+
+	// - refs for comparing a range of commits
+	var from string
+	var to string
+	var since string
+
+	// - how the git repository should be read
+	var gitProvider string
+
+	// - compare against the last successful build of each project
+	var lastSuccessMarker string
+
+	// - detect changes by comparing with a snapshot of the file hashes
+	var useSnapshot bool
+	var snapshotManifest string
+
+	// add the command
+	rootCmd.AddCommand(explainCmd)
+
+	explainCmd.Flags().StringVarP(&cfgFile, "config", "c", "./mrbuild.yaml", "Path to the configuration file for the repository")
+	explainCmd.Flags().StringVar(&explainProject, "project", "", "Explain why the project was, or was not, affected by the changes")
+	explainCmd.Flags().StringVar(&ignore, "ignore", "", "List of projects that should not be processed (command delimited).")
+	explainCmd.Flags().StringVar(&onlyTags, "only-tags", "", "Only build the affected projects that have one of the tags (comma delimited)")
+	explainCmd.Flags().StringVar(&skipTags, "skip-tags", "", "Do not build the affected projects that have any of the tags (comma delimited)")
+	explainCmd.Flags().StringVar(&only, "only", "", "Only build the affected projects with a name that matches the regular expression")
+	explainCmd.Flags().BoolVar(&noDirectives, "no-directives", false, "Do not read directives, such as [skip mrbuild], from the commit message or pull request labels")
+	explainCmd.Flags().StringVar(&datafile, "datafile", "", "Path to file containing git file data to work with")
+	explainCmd.Flags().StringVar(&inputFormat, "input-format", "lines", "Format of the datafile or piped data, lines, nul, porcelain, porcelain-v2, json, github or gitlab")
+	explainCmd.Flags().StringVar(&baseMode, "base-mode", "merge-base", "How changes are compared against the branch, merge-base, tip or range")
+	explainCmd.Flags().StringVar(&from, "from", "", "Ref at the start of the range of commits to compare, implies range mode")
+	explainCmd.Flags().StringVar(&to, "to", "", "Ref at the end of the range of commits to compare, implies range mode")
+	explainCmd.Flags().StringVar(&since, "since", "", "Ref to compare HEAD against, implies range mode")
+	explainCmd.Flags().BoolVar(&useSnapshot, "snapshot", false, "Detect changes by comparing the hashes of the files in each project with the snapshot manifest, instead of using git")
+	explainCmd.Flags().StringVar(&snapshotManifest, "snapshot-manifest", "", "Path to the snapshot manifest file (default \".mrbuild-snapshot.json\")")
+	explainCmd.Flags().StringVar(&lastSuccessMarker, "last-success-marker", "none", "Compare against the last successful build of each project, using none, tag or notes")
+	explainCmd.Flags().StringVar(&gitProvider, "git-provider", "exec", "How the git repository is read, exec to run git or native to read it in-process")
+
+	// the flags are bound in initConfig as they are shared with the affected command
+}
+
+func explainPreRun(ccmd *cobra.Command, args []string) {
+
+	if explainProject == "" && len(args) == 0 {
+		App.Logger.Fatalln("Specify the paths of the files to explain or a project with --project")
+	}
+
+	// the changes are only read when explaining a project
+	if explainProject != "" {
+		affectedPreRun(ccmd, args)
+	}
+}
+
+func executeExplainRun(ccmd *cobra.Command, args []string) {
+
+	if explainProject != "" {
+		explainAffectedProject()
+		return
+	}
+
+	// check the runtime configuration and set defaults
+	err := Config.Check()
+	if err != nil {
+		App.Logger.Fatalln(err.Error())
+	}
+
+	err = discovery.Apply(&Config, App.Logger)
+	if err != nil {
+		App.Logger.Fatalf("Unable to discover projects: %s", err.Error())
+	}
+
+	for i, arg := range args {
+		if i > 0 {
+			fmt.Println()
+		}
+
+		explainFile(getExplainPath(arg))
+	}
+}
+
+// explainFile writes each project, and each of its patterns, with whether they match the file
+func explainFile(file string) {
+
+	fmt.Printf("File: %s\n", file)
+	fmt.Printf("Global trigger: %s\n\n", yesNo(Config.Input.IsGlobalTrigger(file)))
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer writer.Flush()
+
+	fmt.Fprintln(writer, "PROJECT\tMATCHED\tKIND\tPATTERN\tEXPRESSION\tCOMMAND\tRESULT")
+
+	for i := range Config.Input.Projects {
+		project := &Config.Input.Projects[i]
+		matched := yesNo(project.Match(file))
+
+		for _, result := range project.Explain(file) {
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", project.Name, matched, result.Kind, orDash(result.Pattern), result.Expression, orDash(result.Command), getPatternResult(result))
+		}
+	}
+}
+
+// explainAffectedProject detects the changes and writes why the project was, or was not, affected
+func explainAffectedProject() {
+
+	explanation, err := affected.New(&App, &Config, App.Logger).Explain(explainProject)
+	if err != nil {
+		App.Logger.Fatalf("Error running command: %s", err.Error())
+	}
+
+	fmt.Printf("Project: %s\n", explanation.Project)
+	fmt.Printf("Selected: %s\n", yesNo(explanation.Selected))
+
+	for _, section := range []struct {
+		title string
+		items []string
+	}{
+		{"Changed files", explanation.Files},
+		{"Commands", explanation.Commands},
+		{"Reasons", explanation.Reasons},
+	} {
+		fmt.Printf("\n%s:\n", section.title)
+
+		if len(section.items) == 0 {
+			fmt.Println("  -")
+		}
+
+		for _, item := range section.items {
+			fmt.Printf("  %s\n", item)
+		}
+	}
+}
+
+// getExplainPath returns the path in the form that is used for the changed files, which
+// are relative to the root of the repository
+func getExplainPath(file string) string {
+	return strings.TrimPrefix(path.Clean(strings.ReplaceAll(file, "\\", "/")), "./")
+}
+
+// getPatternResult states if the pattern matches the file. An exclude pattern that
+// matches removes the file from the project
+func getPatternResult(result config.PatternResult) string {
+
+	switch {
+	case !result.Matched:
+		return "no match"
+	case result.Kind == config.PatternKindExclude:
+		return "excluded"
+	}
+
+	return "match"
+}
+
+func yesNo(value bool) string {
+	if value {
+		return "yes"
+	}
+
+	return "no"
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
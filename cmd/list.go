@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/amido/mrbuild/internal/config"
+	"github.com/amido/mrbuild/internal/discovery"
+	"github.com/spf13/cobra"
+)
+
+var (
+	listCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List the projects in the configuration and those that have been discovered",
+		Long:  "",
+		Run:   executeListRun,
+	}
+
+	// state if only the discovered projects should be listed
+	listDiscovered bool
+)
+
+func init() {
+
+	// add the command
+	rootCmd.AddCommand(listCmd)
+
+	listCmd.Flags().StringVarP(&cfgFile, "config", "c", "./mrbuild.yaml", "Path to the configuration file for the repository")
+	listCmd.Flags().BoolVar(&listDiscovered, "discovered", false, "Only list the projects that have been discovered, including those overridden by the configuration")
+
+	// the config flag is bound in initConfig as it is shared with other commands
+}
+
+func executeListRun(ccmd *cobra.Command, args []string) {
+
+	// check the runtime configuration and set defaults
+	err := Config.Check()
+	if err != nil {
+		App.Logger.Fatalln(err.Error())
+	}
+
+	discovered, err := discovery.Find(&Config)
+	if err != nil {
+		App.Logger.Fatalf("Unable to discover projects: %s", err.Error())
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer writer.Flush()
+
+	if listDiscovered {
+		fmt.Fprintln(writer, "NAME\tFOLDER\tCOMMAND\tMARKER\tOVERRIDDEN BY")
+
+		for _, project := range discovered {
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n", project.Name, project.Folder, project.Build.Cmd, project.Discovered, discovery.Overridden(Config.Input.Projects, project))
+		}
+
+		return
+	}
+
+	fmt.Fprintln(writer, "NAME\tFOLDER\tCOMMAND\tSOURCE")
+
+	for _, project := range discovery.Merge(Config.Input.Projects, discovered) {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", project.Name, project.Folder, project.Build.Cmd, getSource(project))
+	}
+}
+
+// getSource states where the project has come from
+func getSource(project config.Project) string {
+
+	if project.Discovered != "" {
+		return "discovered: " + project.Discovered
+	}
+
+	return "configured"
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/amido/mrbuild/internal/config"
 	"github.com/amido/mrbuild/internal/constants"
 	"github.com/amido/mrbuild/internal/models"
+	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -25,6 +26,26 @@ var (
 
 	// Set a variable to hold the version number of the application
 	version string
+
+	// Flags that are defined on more than one command, keyed by the configuration setting
+	sharedFlags = map[string]string{
+		"config":               "config",
+		"snapshot.manifest":    "snapshot-manifest",
+		"snapshot.enabled":     "snapshot",
+		"datafile":             "datafile",
+		"inputformat":          "input-format",
+		"options.ignore":       "ignore",
+		"options.onlytags":     "only-tags",
+		"options.skiptags":     "skip-tags",
+		"options.only":         "only",
+		"options.nodirectives": "no-directives",
+		"compare.mode":         "base-mode",
+		"compare.from":         "from",
+		"compare.to":           "to",
+		"compare.since":        "since",
+		"compare.provider":     "git-provider",
+		"baseline.marker":      "last-success-marker",
+	}
 )
 
 var rootCmd = &cobra.Command{
@@ -104,7 +125,17 @@ func initConfig() {
 	viper.AutomaticEnv()
 
 	cmd, _, _ := rootCmd.Find(os.Args[1:])
-	if cmd.Use == affectedCmd.Use {
+
+	// flags that are shared by more than one command are bound to the command that is
+	// being run, otherwise the value from the command that was bound last would be used
+	for key, name := range sharedFlags {
+		if flag := cmd.Flags().Lookup(name); flag != nil {
+			viper.BindPFlag(key, flag)
+		}
+	}
+
+	// read the configuration file for the commands that analyse the repository
+	if cmd.Flags().Lookup("config") != nil {
 		// set the cfgfile from Viper
 		cfgFile = viper.GetString("config")
 
@@ -124,14 +155,22 @@ func initConfig() {
 	}
 }
 
-// preRun is used to ensure that dependencies are in place, such as git
+// preRun is used to read the configuration into the models and configure logging
 func preRun(ccmd *cobra.Command, args []string) {
 
-	err := viper.Unmarshal(&Config.Input)
+	// record the keys that have been set so that a project can override an inherited
+	// setting with the zero value
+	var metadata mapstructure.Metadata
+
+	err := viper.Unmarshal(&Config.Input, func(decoder *mapstructure.DecoderConfig) {
+		decoder.Metadata = &metadata
+	})
 	if err != nil {
 		log.Fatalf("Unable to read configuration into models: %v", err)
 	}
 
+	Config.Input.SetKeys(metadata.Keys)
+
 	// Ensure that the path to the configuration file is set
 	Config.Self.Path = viper.ConfigFileUsed()
 
@@ -142,7 +181,4 @@ func preRun(ccmd *cobra.Command, args []string) {
 
 	// Set the version of the app in the configuration
 	Config.Input.Version = version
-
-	//TODO: Add in check to make sure that git can be found or that the path has been
-	// passed on the command line
 }
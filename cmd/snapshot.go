@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"github.com/amido/mrbuild/internal/discovery"
+	"github.com/amido/mrbuild/internal/snapshot"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	snapshotCmd = &cobra.Command{
+		Use:   "snapshot",
+		Short: "Manage the snapshot of file hashes used to detect changes without git",
+		Long:  "",
+	}
+
+	snapshotSaveCmd = &cobra.Command{
+		Use:   "save",
+		Short: "Save the hashes of the files in each project to the snapshot manifest",
+		Long:  "",
+		Run:   executeSnapshotSaveRun,
+	}
+)
+
+func init() {
+
+	// declare command variables
+
+	// - path to the snapshot manifest
+	var manifest string
+
+	// add the commands
+	rootCmd.AddCommand(snapshotCmd)
+	snapshotCmd.AddCommand(snapshotSaveCmd)
+
+	snapshotSaveCmd.Flags().StringVarP(&cfgFile, "config", "c", "./mrbuild.yaml", "Path to the configuration file for the repository")
+	snapshotSaveCmd.Flags().StringVar(&manifest, "snapshot-manifest", "", "Path to the snapshot manifest file (default \".mrbuild-snapshot.json\")")
+
+	// the flags are bound in initConfig as they are shared with the affected command
+}
+
+func executeSnapshotSaveRun(ccmd *cobra.Command, args []string) {
+
+	// check the runtime configuration and set defaults
+	err := Config.Check()
+	if err != nil {
+		App.Logger.Fatalln(err.Error())
+	}
+
+	// the folders of the discovered projects are included in the snapshot
+	err = discovery.Apply(&Config, App.Logger)
+	if err != nil {
+		App.Logger.Fatalf("Unable to discover projects: %s", err.Error())
+	}
+
+	manifest, err := snapshot.Take(&Config)
+	if err != nil {
+		App.Logger.Fatalf("Unable to create snapshot: %s", err.Error())
+	}
+
+	path := snapshot.GetManifestPath(&Config)
+
+	App.Logger.WithFields(
+		log.Fields{
+			"manifest": path,
+			"files":    len(manifest.Files),
+		},
+	).Info("Saving snapshot manifest")
+
+	// do not overwrite the manifest when in DryRun mode
+	if Config.IsDryRun() {
+		App.Logger.Warn("Not saving snapshot manifest as in DryRun mode")
+		return
+	}
+
+	err = manifest.Save(path)
+	if err != nil {
+		App.Logger.Fatalf("Unable to save snapshot manifest: %s", err.Error())
+	}
+}
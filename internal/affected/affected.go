@@ -5,20 +5,52 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
-	"regexp"
+	"path/filepath"
 	"sort"
+	"strings"
+	"sync"
 
 	"github.com/amido/mrbuild/internal/config"
+	"github.com/amido/mrbuild/internal/discovery"
+	"github.com/amido/mrbuild/internal/git"
+	"github.com/amido/mrbuild/internal/graph"
 	"github.com/amido/mrbuild/internal/models"
+	"github.com/amido/mrbuild/internal/parser"
+	"github.com/amido/mrbuild/internal/scheduler"
 	"github.com/amido/mrbuild/internal/util"
 	"github.com/sirupsen/logrus"
 	log "github.com/sirupsen/logrus"
 )
 
 type Affected struct {
-	App    *models.App
-	Config *config.Config
-	Logger *logrus.Logger
+	App      *models.App
+	Config   *config.Config
+	Logger   *logrus.Logger
+	Provider git.Provider // Provider used to read the changes from the git repository
+
+	// reason that all projects should be built, regardless of the changes
+	buildAll string
+
+	// commits that were compared to get the changes from git, to is empty
+	// if the comparison was made with the working tree
+	from string
+	to   string
+
+	// instructions for the run from the commit message or pull request labels
+	directives directives
+
+	// dependencies between the projects
+	graph *graph.Graph
+
+	// notes that record the last successful build of each project, and a lock so
+	// that only one marker is written at a time
+	notes      []git.Note
+	markerLock sync.Mutex
+
+	// reasons that each project was, or was not, selected to be built, and the changed
+	// files that matched each project
+	reasons map[string][]string
+	matched map[string][]string
 }
 
 // New allocates a new AffectedPointer to the given config
@@ -34,6 +66,48 @@ func New(app *models.App, conf *config.Config, logger *logrus.Logger) *Affected
 // look decide which folder have changed and then perform a build
 // within those folders according\ to the config file
 func (a *Affected) Run() error {
+
+	// determine if any of the files match the patterns specified for the project
+	affectedProjects, err := a.analyse()
+	if err != nil {
+		return err
+	}
+
+	a.App.Logger.Debugf("Analysing %d projects", len(affectedProjects))
+
+	// build the graph of the builds from the order tiers and the dependencies of the projects
+	schedule, err := scheduler.New(affectedProjects)
+	if err != nil {
+		return err
+	}
+
+	// Configure the worker pool
+	// As each project will have its own build mechanism a pool of workers is setup to run
+	// each build on a concurrent thread, a build is only started once its prerequisites have succeeded
+	a.App.ConfigureWorkers(a.Config.Input.Pool.Workers)
+
+	results := schedule.Run(a.App.Workers, a.runBuild)
+
+	// wait for all the jobs to complete
+	a.App.Workers.StopWait()
+
+	for _, result := range results {
+		if result.Status == scheduler.StatusSkipped {
+			a.App.Logger.WithFields(
+				log.Fields{
+					"project": result.Name,
+					"status":  result.Status,
+				},
+			).Warn(result.Reason)
+		}
+	}
+
+	return err
+}
+
+// analyse checks the configuration, reads the changes and returns the projects that
+// have been affected by them
+func (a *Affected) analyse() ([]models.SpawnBuild, error) {
 	var err error
 
 	// check the runtime configuration and set defaults
@@ -42,95 +116,173 @@ func (a *Affected) Run() error {
 		a.Logger.Fatalln(err.Error())
 	}
 
+	// add the projects that can be discovered from the marker files in the repository
+	err = discovery.Apply(a.Config, a.Logger)
+	if err != nil {
+		return nil, err
+	}
+
+	// build the graph of the dependencies between the projects, which reports any cycles
+	a.graph, err = graph.New(a.Config.Input.Projects)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.Config.CI.System != "" {
+		a.Logger.WithFields(
+			log.Fields{
+				"system":      a.Config.CI.System,
+				"baseBranch":  a.Config.CI.BaseBranch,
+				"headSha":     a.Config.CI.HeadSha,
+				"pullRequest": a.Config.CI.PullRequest,
+				"buildId":     a.Config.CI.BuildID,
+			},
+		).Info("Detected CI/CD system")
+	}
+
+	// read the directives from the commit message and pull request labels so that
+	// they can change which projects are built
+	a.applyDirectives()
+
 	// if a datafile has been specified, read in the data
 	// otherwise run the git command to get a list of the changed files
 	list, err := a.getFiles()
+	if err != nil {
+		return nil, err
+	}
 
-	// determine if any of the files match the patterns specified for the project
-	affectedProjects := a.getProjects(list)
+	return a.getProjects(list), nil
+}
 
-	a.App.Logger.Debugf("Analysing %d projects", len(affectedProjects))
+// runBuild runs the commands of the affected project, stopping at the first one that fails,
+// and records the success of the build
+func (a *Affected) runBuild(p models.SpawnBuild) error {
 
-	// Configure the worker pool
-	// As each project will have its own build mechanism a pool of workers is setup to run
-	// each build on a concurrent thread
-	a.App.ConfigureWorkers(a.Config.Input.Pool.Workers)
+	env := p.GetEnv()
+
+	// the files are always written to a file, as the list may be too large to be
+	// passed in an environment variable
+	if !a.Config.IsDryRun() {
+		path, err := a.writeAffectedFiles(p)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(path)
+
+		env[models.AffectedFilesPathEnvVar] = path
+	}
+
+	if !p.AffectedFilesFit() {
+		a.App.Logger.WithFields(
+			log.Fields{
+				"project": p.Name,
+				"files":   len(p.Files),
+			},
+		).Warnf("Too many affected files to set %s, use %s instead", models.AffectedFilesEnvVar, models.AffectedFilesPathEnvVar)
+	}
 
-	// iterate around the affected projects and spawn each build process
-	for _, p := range affectedProjects {
+	for _, command := range p.GetCommands() {
 
 		// Output the command that is to be run along with the directory it will be run in
 		a.App.Logger.WithFields(
 			log.Fields{
 				"workingDir": p.Directory,
 				"project":    p.Name,
-				"command":    p.GetCommand(),
+				"command":    command,
+				"deleted":    p.Deleted,
+				"files":      p.Files,
 			},
 		).Info("Executing command")
 
 		if a.Config.IsDryRun() {
 			a.App.Logger.Warn("Not running command as in DryRun mode")
-		} else {
+			continue
+		}
 
-			// get the command parts
-			cmd, args := p.GetCommandParts()
-
-			// submit the command to be run
-			a.App.Workers.Submit(func() {
-				output, err := a.Config.ExecuteCommand(
-					p.Directory,
-					a.Logger,
-					cmd,
-					args,
-					true,
-					false,
-				)
-
-				if err == nil {
-					a.App.Logger.Info(output)
-				} else {
-					a.App.Logger.Error(err.Error())
-				}
-			})
+		// get the command parts
+		cmd, args := models.CommandParts(command)
+
+		output, err := a.Config.ExecuteCommandEnv(
+			p.Directory,
+			a.Logger,
+			cmd,
+			args,
+			env,
+			true,
+			false,
+		)
+
+		if err != nil {
+			a.App.Logger.Error(err.Error())
+			return err
 		}
+
+		a.App.Logger.Info(output)
 	}
 
-	// wait for all the jobs to complete
-	a.App.Workers.StopWait()
+	if !p.Deleted && !a.Config.IsDryRun() {
+		a.recordSuccess(p.Name)
+	}
 
-	return err
+	return nil
+}
+
+// writeAffectedFiles writes the files that caused the project to be affected to a temporary
+// file, one per line, and returns its path
+func (a *Affected) writeAffectedFiles(p models.SpawnBuild) (string, error) {
+
+	file, err := os.CreateTemp("", "mrbuild-affected-*.txt")
+	if err != nil {
+		return "", fmt.Errorf("unable to write the affected files of project %s: %s", p.Name, err.Error())
+	}
+	defer file.Close()
+
+	// each line is terminated so that the last file is read by tools such as wc and read
+	content := p.AffectedFiles()
+	if content != "" {
+		content += "\n"
+	}
+
+	if _, err = file.WriteString(content); err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("unable to write the affected files of project %s: %s", p.Name, err.Error())
+	}
+
+	return file.Name(), nil
 }
 
 // getFiles returns a list of files that are affected in this branch
-// this can be done by reading the datafile, if it has been specified or by
-// running the git command to get the list
-func (a *Affected) getFiles() (string, error) {
+// this can be done by reading the datafile, if it has been specified, comparing
+// the files with a snapshot or by running the git command to get the list
+func (a *Affected) getFiles() ([]models.ChangedFile, error) {
 
 	var content []byte
-	var files string
+	var files []models.ChangedFile
 	var err error
 
 	// if running in pipe mode get the data from stdnin
 	if util.IsInputFromPipe() {
 
-		data, err := io.ReadAll(os.Stdin)
+		data, rerr := io.ReadAll(os.Stdin)
 
-		if err != nil {
+		if rerr != nil {
 			a.Logger.Errorf("Unable to read content from pipe")
+			err = fmt.Errorf("unable to read content from pipe: %s", rerr.Error())
 		} else {
-			files = string(data)
+			files, err = a.parseInput(data)
+		}
+	} else if a.Config.Input.Snapshot.Enabled {
+
+		// compare the content of the files with the snapshot from the last successful run
+		files, err = a.getSnapshotFiles()
+
+		if err != nil {
+			a.Logger.Errorf("Unable to compare changes with snapshot: %s", err.Error())
 		}
 	} else if a.Config.Input.Datafile == "" {
 
-		// execute the command
-		files, err = a.Config.ExecuteCommand(
-			a.Config.Input.Directory.WorkingDir,
-			a.Logger,
-			"git",
-			fmt.Sprintf("--no-pager diff --name-only %s", a.Config.Input.Branch),
-			false,
-			false,
-		)
+		// execute the git command to compare against the branch
+		files, err = a.getGitFiles()
 
 		if err != nil {
 			a.Logger.Errorf("Issue running command: %s", err.Error())
@@ -139,66 +291,139 @@ func (a *Affected) getFiles() (string, error) {
 
 		// attempt to read in the file
 		content, err = ioutil.ReadFile(a.Config.Input.Datafile)
-		files = string(content)
+		if err == nil {
+			files, err = a.parseInput(content)
+		}
 	}
 
 	return files, err
 }
 
+// parseInput converts the data from the datafile or pipe into a list of files using
+// the input format that has been specified
+func (a *Affected) parseInput(data []byte) ([]models.ChangedFile, error) {
+
+	files, err := parser.Parse(a.Config.Input.InputFormat, data)
+	if err != nil {
+		a.Logger.Errorf("Unable to parse input data: %s", err.Error())
+		return nil, err
+	}
+
+	a.Logger.WithFields(
+		log.Fields{
+			"format": a.Config.Input.InputFormat,
+			"count":  len(files),
+		},
+	).Debug("Parsed input data")
+
+	return files, nil
+}
+
 // getProjects iterates around the projects that have been defined in the configuration
 // file and determine if any of the them have been changed
 // If they have then find the command for the project and add to an array along
 // with the project directory
-func (a *Affected) getProjects(list string) []models.SpawnBuild {
+func (a *Affected) getProjects(files []models.ChangedFile) []models.SpawnBuild {
 
-	var re *regexp.Regexp
 	var spawns []models.SpawnBuild
 
-	// determine the path to the project, this is based on the location of the
+	if a.directives.Skip {
+		a.App.Logger.Warn("Not building any projects as the skip directive has been set")
+		for _, project := range a.Config.Input.Projects {
+			a.explain(project.Name, "not built as the skip directive has been set")
+		}
+		return spawns
+	}
+
+	// a change to a global trigger affects every project
+	if a.buildAll == "" {
+		if file := a.getGlobalTrigger(files); file != "" {
+			a.buildAll = fmt.Sprintf("global trigger: %s", file)
+		}
+	}
+
+	if a.buildAll != "" {
+		a.App.Logger.Warnf("Building all projects: %s", a.buildAll)
+	}
 
 	// iterate around the projects
 	for _, project := range a.Config.Input.Projects {
 
 		// check to see if hte project is to be ignored
-		if a.Config.Input.Options.IgnoreProject(project.Name) {
+		if a.isIgnored(project.Name) {
 			a.App.Logger.Warnf("Ignoring project: %s", project.Name)
+			if a.directives.isIgnored(project.Name) {
+				a.explain(project.Name, "ignored by the ignore directive")
+			} else {
+				a.explain(project.Name, "ignored by the ignore option")
+			}
 			continue
 		}
 
-		// use the project folder and the patterns to try and match with the data
-		for _, pattern := range project.Patterns {
+		// projects that have been requested by a directive are built regardless of the changes
+		forced := a.buildAll != ""
+		if forced {
+			a.explain(project.Name, "built as all projects are being built: %s", a.buildAll)
+		} else if a.directives.isBuilt(project.Name) {
+			a.App.Logger.Infof("Building project as requested by directive: %s", project.Name)
+			a.explain(project.Name, "built as requested by the build directive")
+			forced = true
+		}
+
+		// compare the project against its last successful build, if one has been recorded
+		projectFiles, from := files, a.from
+		if marker, markerFiles, ok := a.getMarkerFiles(project); ok {
+			projectFiles, from = markerFiles, marker
+			a.explain(project.Name, "compared against the last successful build at %s", marker)
+		}
 
-			_pattern := fmt.Sprintf("(?m)%s/%s", project.Folder, pattern)
+		// match each of the files against the patterns of the project
+		matched, paths := a.getMatchedFiles(project, projectFiles)
+		if len(paths) > 0 {
+			if a.matched == nil {
+				a.matched = make(map[string][]string)
+			}
+			a.matched[project.Name] = paths
+			a.explain(project.Name, "changed files match the project: %s", strings.Join(paths, ", "))
+		}
 
-			re = regexp.MustCompile(_pattern)
+		if !forced && len(matched) == 0 {
+			a.explain(project.Name, "none of the changed files match the project")
+			continue
+		}
 
-			// determine if the regular expression matches the list of files
-			matches := re.MatchString(list)
-			if matches {
+		// skip the project if the changes are only to whitespace or comments
+		if !forced && project.IgnoreTrivial && a.isTrivial(project, matched, from) {
+			a.App.Logger.Infof("Skipping project as the changes are trivial: %s", project.Name)
+			a.explain(project.Name, "not built as the changes are trivial")
+			continue
+		}
 
-				// determine the path that the build should be run in
-				folder := project.Build.Folder
+		// a project that is built regardless of the changes runs all of its commands
+		commandFiles := paths
+		if forced {
+			commandFiles = nil
+		}
 
-				// if folder is . then set as the path to the configuration file
-				// if it is null then use the project folder
-				if folder == "" {
-					folder = project.Folder
-				} else if folder == "." {
-					folder = a.Config.Self.GetDir()
-				}
+		spawn, ok := a.getSpawnBuild(project, projectFiles, commandFiles)
+		if ok {
+			spawn.Files = paths
+			spawns = append(spawns, spawn)
+		}
+	}
 
-				spawns = append(spawns, models.SpawnBuild{
-					Name:      project.Name,
-					Command:   project.Build.Cmd,
-					Directory: folder,
-					Env:       project.Env,
-					Order:     project.Order,
-				})
+	// the projects that depend on the affected projects are affected as well
+	if a.buildAll == "" {
+		spawns = append(spawns, a.getDependents(spawns, files)...)
+	}
 
-				// as a match has been found, exit out of the inner loop and move
-				// onto the next project
-				break
-			}
+	// only build the projects that have been selected by their tags or name
+	spawns = a.selectProjects(spawns)
+
+	// a project is only built once the projects that it depends on have been built
+	if projectGraph := a.getGraph(); projectGraph != nil {
+		for i := range spawns {
+			spawns[i].DependsOn = projectGraph.AllDependencies(spawns[i].Name)
 		}
 	}
 
@@ -209,3 +434,228 @@ func (a *Affected) getProjects(list string) []models.SpawnBuild {
 
 	return spawns
 }
+
+// getDependents returns the projects that depend, directly or transitively, on one of the
+// affected projects. The chain of projects that each one is affected via is written to the log
+func (a *Affected) getDependents(spawns []models.SpawnBuild, files []models.ChangedFile) []models.SpawnBuild {
+
+	var dependents []models.SpawnBuild
+
+	projectGraph := a.getGraph()
+	if projectGraph == nil {
+		return dependents
+	}
+
+	var affected []string
+	for _, spawn := range spawns {
+		affected = append(affected, spawn.Name)
+	}
+
+	chains := projectGraph.Propagate(affected)
+
+	for _, project := range a.Config.Input.Projects {
+
+		chain, ok := chains[project.Name]
+		if !ok || a.isIgnored(project.Name) {
+			continue
+		}
+
+		reason := fmt.Sprintf("%s affected via %s", project.Name, strings.Join(chain, " via "))
+		a.explain(project.Name, reason)
+
+		a.App.Logger.WithFields(
+			log.Fields{
+				"project": project.Name,
+				"reason":  reason,
+			},
+		).Info("Building project as a dependency has been affected")
+
+		if spawn, ok := a.getSpawnBuild(project, files, nil); ok {
+			dependents = append(dependents, spawn)
+		}
+	}
+
+	return dependents
+}
+
+// selectProjects returns the affected projects that have been selected by the tags and
+// name options. The selection is made after the changes have been detected so that each
+// stage of a pipeline can build a different set of the affected projects
+func (a *Affected) selectProjects(spawns []models.SpawnBuild) []models.SpawnBuild {
+
+	var selected []models.SpawnBuild
+
+	for _, spawn := range spawns {
+		for _, project := range a.Config.Input.Projects {
+			if project.Name != spawn.Name {
+				continue
+			}
+
+			if ok, reason := a.Config.Input.Options.SelectProject(project); !ok {
+				a.explain(project.Name, "not selected as it %s", reason)
+				a.App.Logger.WithFields(
+					log.Fields{
+						"project": project.Name,
+						"reason":  reason,
+					},
+				).Info("Not building project as it has not been selected")
+				break
+			}
+
+			selected = append(selected, spawn)
+			break
+		}
+	}
+
+	return selected
+}
+
+// isIgnored states if the project has been ignored by the ignore option or by a directive
+func (a *Affected) isIgnored(project string) bool {
+	return a.Config.Input.Options.IgnoreProject(project) || a.directives.isIgnored(project)
+}
+
+// explain records a reason that the project was, or was not, selected to be built so that
+// it can be reported by the explain command
+func (a *Affected) explain(project string, format string, args ...interface{}) {
+
+	if a.reasons == nil {
+		a.reasons = make(map[string][]string)
+	}
+
+	a.reasons[project] = append(a.reasons[project], fmt.Sprintf(format, args...))
+}
+
+// getGraph returns the graph of the dependencies between the projects, building it the
+// first time that it is requested. Nil is returned if the graph cannot be built
+func (a *Affected) getGraph() *graph.Graph {
+
+	if a.graph == nil {
+		var err error
+		if a.graph, err = graph.New(a.Config.Input.Projects); err != nil {
+			a.Logger.Warnf("Unable to determine the dependencies of the projects: %s", err.Error())
+			return nil
+		}
+	}
+
+	return a.graph
+}
+
+// getGlobalTrigger returns the first file that matches one of the global triggers, or
+// that belongs to a project that triggers all of the projects. An ignored project does
+// not trigger the others
+func (a *Affected) getGlobalTrigger(files []models.ChangedFile) string {
+
+	for _, file := range files {
+		for _, path := range file.GetPaths() {
+			if a.Config.Input.IsGlobalTrigger(path) {
+				return path
+			}
+
+			for _, project := range a.Config.Input.Projects {
+				if project.TriggersAll && !a.isIgnored(project.Name) && project.Match(path) {
+					return path
+				}
+			}
+		}
+	}
+
+	return ""
+}
+
+// getMatchedFiles returns the changed files that belong to the project, and the paths
+// that were matched. The old path of a renamed file is also checked, so that moving a
+// file out of a project affects it
+func (a *Affected) getMatchedFiles(project config.Project, files []models.ChangedFile) ([]models.ChangedFile, []string) {
+
+	var matched []models.ChangedFile
+	var paths []string
+
+	for _, file := range files {
+		found := false
+
+		for _, path := range file.GetPaths() {
+			if project.Match(path) {
+				paths = append(paths, path)
+				found = true
+			}
+		}
+
+		if found {
+			matched = append(matched, file)
+		}
+	}
+
+	return matched, paths
+}
+
+// getSpawnBuild creates the SpawnBuild for the project that has been affected
+// The commands are those of the groups that match the paths, or all of them if there are no paths
+// If the project has been removed and does not have an on_delete command, there is
+// nothing to run and false is returned
+func (a *Affected) getSpawnBuild(project config.Project, files []models.ChangedFile, paths []string) (models.SpawnBuild, bool) {
+
+	// determine the path that the build should be run in
+	folder := project.Build.Folder
+
+	// if folder is . then set as the path to the configuration file
+	// if it is null then use the project folder
+	if folder == "" {
+		folder = project.Folder
+	} else if folder == "." {
+		folder = a.Config.Self.GetDir()
+	}
+
+	spawn := models.SpawnBuild{
+		Name:      project.Name,
+		Command:   project.Build.Cmd,
+		Commands:  project.GetCommands(paths),
+		Directory: folder,
+		Env:       project.Env,
+		Order:     project.Order,
+		BuildAll:  a.buildAll,
+	}
+
+	// if the project has been removed the build command cannot be run, so
+	// the on_delete command is run instead, if one has been set
+	if a.isRemoved(project, files) {
+		if project.OnDelete == "" {
+			a.App.Logger.Warnf("Project has been removed and no on_delete command has been set: %s", project.Name)
+			a.explain(project.Name, "not built as the project has been removed and no on_delete command has been set")
+			return spawn, false
+		}
+
+		a.explain(project.Name, "the project has been removed so the on_delete command is run")
+
+		// the project folder no longer exists so run the command from the configuration directory
+		if folder == project.Folder {
+			spawn.Directory = a.Config.Self.GetDir()
+		}
+
+		spawn.Command = project.OnDelete
+		spawn.Commands = nil
+		spawn.Deleted = true
+	}
+
+	return spawn, true
+}
+
+// isRemoved determines if the project has been removed from the repository
+// This is the case when the folder for the project no longer exists and files
+// in the folder have been deleted or renamed
+func (a *Affected) isRemoved(project config.Project, files []models.ChangedFile) bool {
+
+	if util.Exists(filepath.Join(a.Config.Input.Directory.WorkingDir, project.Folder)) {
+		return false
+	}
+
+	prefix := strings.TrimSuffix(project.Folder, "/") + "/"
+
+	for _, file := range files {
+		if strings.HasPrefix(file.GetRemovedPath(), prefix) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,10 +1,12 @@
 package affected
 
 import (
+	"os"
 	"testing"
 
 	"github.com/amido/mrbuild/internal/config"
 	"github.com/amido/mrbuild/internal/models"
+	"github.com/amido/mrbuild/internal/parser"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 )
@@ -243,7 +245,7 @@ func TestGetProjectsOrdering(t *testing.T) {
 			affected := New(app, cfg, logger)
 
 			// Call getProjects
-			spawns := affected.getProjects(tt.changedFiles)
+			spawns := affected.getProjects(parser.ParseLines(tt.changedFiles))
 
 			// Verify the number of spawns
 			assert.Equal(t, len(tt.expectedOrder), len(spawns),
@@ -301,7 +303,7 @@ func TestGetProjectsOrderFieldPreservation(t *testing.T) {
 	affected := New(app, cfg, logger)
 
 	changedFiles := "src/p1/main.go\nsrc/p2/main.go"
-	spawns := affected.getProjects(changedFiles)
+	spawns := affected.getProjects(parser.ParseLines(changedFiles))
 
 	assert.Equal(t, 2, len(spawns), "Should have 2 spawns")
 
@@ -343,7 +345,7 @@ func TestGetProjectsSortStability(t *testing.T) {
 
 	// All projects affected
 	changedFiles := "src/alpha/f\nsrc/beta/f\nsrc/gamma/f\nsrc/delta/f"
-	spawns := affected.getProjects(changedFiles)
+	spawns := affected.getProjects(parser.ParseLines(changedFiles))
 
 	// The order should be preserved as Go's sort.Slice is stable
 	expectedOrder := []string{"alpha", "beta", "gamma", "delta"}
@@ -355,3 +357,435 @@ func TestGetProjectsSortStability(t *testing.T) {
 	assert.Equal(t, expectedOrder, actualOrder,
 		"Sort should be stable - projects with same order value should maintain their original relative order")
 }
+
+// TestGetProjectsBuildAll tests that all projects that are not ignored are built when requested
+func TestGetProjectsBuildAll(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	projects := []config.Project{
+		{Name: "api", Folder: "src/api", Patterns: []string{".*\\.go"}, Build: config.Build{Cmd: "echo api"}},
+		{Name: "web", Folder: "src/web", Patterns: []string{".*\\.js"}, Build: config.Build{Cmd: "echo web"}},
+		{Name: "infra", Folder: "src/infra", Patterns: []string{".*\\.tf"}, Build: config.Build{Cmd: "echo infra"}},
+	}
+
+	cfg := &config.Config{
+		Input: config.InputConfig{
+			Projects: projects,
+			Options:  config.Options{Ignore: "infra"},
+		},
+	}
+
+	affected := New(&models.App{Logger: logger}, cfg, logger)
+	affected.buildAll = "unit test"
+
+	spawns := affected.getProjects(nil)
+
+	actual := make([]string, len(spawns))
+	for i, spawn := range spawns {
+		actual[i] = spawn.Name
+	}
+
+	assert.Equal(t, []string{"api", "web"}, actual, "All projects that are not ignored should be built")
+}
+
+// TestGetProjectsGlobs tests that globs can be used instead of, or as well as, regular expressions
+func TestGetProjectsGlobs(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	cfg := &config.Config{
+		Input: config.InputConfig{
+			Projects: []config.Project{
+				{Name: "infra", Folder: "src/infra", Globs: []string{"**/*.tf", "!**/examples/**"}},
+				{Name: "docs", Folder: "docs", Globs: []string{"!**/*.png"}},
+				{Name: "api", Folder: "src/api", Patterns: []string{".*\\.go"}, Globs: []string{"**/*.proto"}},
+				{Name: "web", Folder: "src/web", Patterns: []string{".*\\.js"}},
+			},
+		},
+	}
+
+	tables := []struct {
+		name     string
+		files    string
+		expected []string
+	}{
+		{"glob matches", "src/infra/modules/vpc/main.tf", []string{"infra"}},
+		{"negated glob", "src/infra/examples/main.tf\ndocs/images/logo.png", []string{}},
+		{"only negations", "docs/usage.adoc", []string{"docs"}},
+		{"regex and glob", "src/api/proto/api.proto\nsrc/web/app.js", []string{"api", "web"}},
+	}
+
+	for _, table := range tables {
+		affected := New(&models.App{Logger: logger}, cfg, logger)
+
+		actual := []string{}
+		for _, spawn := range affected.getProjects(parser.ParseLines(table.files)) {
+			actual = append(actual, spawn.Name)
+		}
+
+		assert.Equal(t, table.expected, actual, table.name)
+	}
+}
+
+// TestGetProjectsExclude tests that excluded files never affect a project, even if a pattern matches them
+func TestGetProjectsExclude(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	cfg := &config.Config{
+		Input: config.InputConfig{
+			Projects: []config.Project{
+				{Name: "api", Folder: "src/api", Patterns: []string{".*"}, Exclude: []string{"README.md", "docs/", "*_test.fixture"}},
+				{Name: "infra", Folder: "src/infra", Globs: []string{"**"}, Exclude: []string{"*.md"}},
+			},
+		},
+	}
+
+	tables := []struct {
+		name     string
+		files    string
+		expected []string
+	}{
+		{"only excluded files", "src/api/README.md\nsrc/api/docs/usage.md\nsrc/api/testdata/users_test.fixture\nsrc/infra/README.md", []string{}},
+		{"excluded and included files", "src/api/README.md\nsrc/api/main.go", []string{"api"}},
+		{"glob with exclude", "src/infra/docs/setup.md\nsrc/infra/main.tf", []string{"infra"}},
+	}
+
+	for _, table := range tables {
+		affected := New(&models.App{Logger: logger}, cfg, logger)
+
+		actual := []string{}
+		for _, spawn := range affected.getProjects(parser.ParseLines(table.files)) {
+			actual = append(actual, spawn.Name)
+		}
+
+		assert.Equal(t, table.expected, actual, table.name)
+	}
+}
+
+// TestGetProjectsFiles tests that each project carries the files that caused it to be affected
+func TestGetProjectsFiles(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	cfg := &config.Config{
+		Input: config.InputConfig{
+			Projects: []config.Project{
+				{Name: "api", Folder: "src/api", Patterns: []string{".*\\.go"}},
+				{Name: "web", Folder: "src/web", Patterns: []string{".*"}},
+			},
+		},
+	}
+
+	files := []models.ChangedFile{
+		{Status: models.ChangeModified, Path: "src/api/main.go"},
+		{Status: models.ChangeModified, Path: "src/api/README.md"},
+		{Status: models.ChangeModified, Path: "legacy/src/api/old.go"},
+		{Status: models.ChangeAdded, Path: "src/api/handler.go"},
+		{Status: models.ChangeModified, Path: "src/web/app.js"},
+	}
+
+	affected := New(&models.App{Logger: logger}, cfg, logger)
+	spawns := affected.getProjects(files)
+
+	assert.Equal(t, 2, len(spawns))
+	assert.Equal(t, []string{"src/api/main.go", "src/api/handler.go"}, spawns[0].Files)
+	assert.Equal(t, []string{"src/web/app.js"}, spawns[1].Files)
+}
+
+// TestGetProjectsGlobalTriggers tests that a change to a global trigger, or to a project that
+// triggers all projects, affects every project that has not been ignored
+func TestGetProjectsGlobalTriggers(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	tables := []struct {
+		name     string
+		files    string
+		ignore   string
+		expected []string
+		reason   string
+	}{
+		{"no trigger", "src/api/main.go", "", []string{"api"}, ""},
+		{"global trigger", "build/version.sh", "", []string{"api", "web", "shared"}, "global trigger: build/version.sh"},
+		{"global trigger with ignore", "build/version.sh", "web", []string{"api", "shared"}, "global trigger: build/version.sh"},
+		{"project triggers all", "src/shared/util.go", "", []string{"api", "web", "shared"}, "global trigger: src/shared/util.go"},
+		{"ignored project does not trigger all", "src/shared/util.go", "shared", []string{}, ""},
+	}
+
+	for _, table := range tables {
+		cfg := &config.Config{
+			Input: config.InputConfig{
+				GlobalTriggers: []string{"build/"},
+				Projects: []config.Project{
+					{Name: "api", Folder: "src/api", Patterns: []string{".*"}},
+					{Name: "web", Folder: "src/web", Patterns: []string{".*"}},
+					{Name: "shared", Folder: "src/shared", Patterns: []string{".*"}, TriggersAll: true},
+				},
+				Options: config.Options{Ignore: table.ignore},
+			},
+		}
+
+		affected := New(&models.App{Logger: logger}, cfg, logger)
+
+		actual := []string{}
+		for _, spawn := range affected.getProjects(parser.ParseLines(table.files)) {
+			actual = append(actual, spawn.Name)
+		}
+
+		assert.Equal(t, table.expected, actual, table.name)
+		assert.Equal(t, table.reason, affected.buildAll, table.name)
+	}
+}
+
+// TestGetProjectsInputs tests that changes to the inputs of a project, which are outside of its folder, affect it
+func TestGetProjectsInputs(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	cfg := &config.Config{
+		Input: config.InputConfig{
+			Projects: []config.Project{
+				{Name: "api", Folder: "src/api", Patterns: []string{".*"}, Inputs: []string{"shared/proto/**", "infra/modules/network"}},
+				{Name: "web", Folder: "src/web", Patterns: []string{".*"}},
+			},
+		},
+	}
+
+	tables := []struct {
+		name     string
+		files    string
+		expected []string
+	}{
+		{"input glob", "shared/proto/users.proto", []string{"api"}},
+		{"input directory", "infra/modules/network/main.tf", []string{"api"}},
+		{"outside inputs", "infra/modules/dns/main.tf", []string{}},
+		{"input and folder", "shared/proto/users.proto\nsrc/web/app.js", []string{"api", "web"}},
+	}
+
+	for _, table := range tables {
+		affected := New(&models.App{Logger: logger}, cfg, logger)
+
+		actual := []string{}
+		for _, spawn := range affected.getProjects(parser.ParseLines(table.files)) {
+			actual = append(actual, spawn.Name)
+		}
+
+		assert.Equal(t, table.expected, actual, table.name)
+	}
+}
+
+// TestGetProjectsDependencies tests that the projects that depend on an affected project are also affected
+func TestGetProjectsDependencies(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	cfg := &config.Config{
+		Input: config.InputConfig{
+			Projects: []config.Project{
+				{Name: "lib-common", Folder: "libs/common", Patterns: []string{".*"}},
+				{Name: "api", Folder: "src/api", Patterns: []string{".*"}, DependsOn: []string{"lib-common"}},
+				{Name: "web", Folder: "src/web", Patterns: []string{".*"}, DependsOn: []string{"api"}},
+				{Name: "docs", Folder: "docs", Patterns: []string{".*"}},
+			},
+		},
+	}
+
+	tables := []struct {
+		name     string
+		files    string
+		ignore   string
+		expected []string
+	}{
+		{"transitive dependents", "libs/common/util.go", "", []string{"lib-common", "api", "web"}},
+		{"direct dependent", "src/api/main.go", "", []string{"api", "web"}},
+		{"no dependents", "src/web/app.js\ndocs/index.md", "", []string{"web", "docs"}},
+		{"ignored dependent", "libs/common/util.go", "api", []string{"lib-common", "web"}},
+	}
+
+	for _, table := range tables {
+		cfg.Input.Options.Ignore = table.ignore
+		affected := New(&models.App{Logger: logger}, cfg, logger)
+
+		actual := []string{}
+		for _, spawn := range affected.getProjects(parser.ParseLines(table.files)) {
+			actual = append(actual, spawn.Name)
+		}
+
+		assert.Equal(t, table.expected, actual, table.name)
+	}
+}
+
+// TestGetProjectsSelection tests that the tags and name selectors are applied to the affected projects
+func TestGetProjectsSelection(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	tables := []struct {
+		name     string
+		options  config.Options
+		expected []string
+	}{
+		{"all affected", config.Options{}, []string{"network", "api", "web"}},
+		{"infra stage", config.Options{OnlyTags: "infra"}, []string{"network"}},
+		{"apps stage", config.Options{SkipTags: "infra"}, []string{"api", "web"}},
+		{"only name", config.Options{Only: "web"}, []string{"web"}},
+	}
+
+	for _, table := range tables {
+		cfg := &config.Config{
+			Input: config.InputConfig{
+				Projects: []config.Project{
+					{Name: "network", Folder: "infra/network", Patterns: []string{".*"}, Tags: []string{"infra"}},
+					{Name: "lib", Folder: "libs/common", Patterns: []string{".*"}, Tags: []string{"apps"}},
+					{Name: "api", Folder: "src/api", Patterns: []string{".*"}, Tags: []string{"apps"}},
+					{Name: "web", Folder: "src/web", Patterns: []string{".*"}, Tags: []string{"apps"}, DependsOn: []string{"api"}},
+				},
+				Options: table.options,
+			},
+		}
+
+		affected := New(&models.App{Logger: logger}, cfg, logger)
+
+		actual := []string{}
+		for _, spawn := range affected.getProjects(parser.ParseLines("infra/network/main.tf\nsrc/api/main.go")) {
+			actual = append(actual, spawn.Name)
+		}
+
+		assert.Equal(t, table.expected, actual, table.name)
+	}
+}
+
+// TestGetProjectsCommands tests that the commands of the groups that match the changed files are run
+func TestGetProjectsCommands(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	tables := []struct {
+		name     string
+		files    string
+		buildAll string
+		expected []string
+	}{
+		{"docs only", "src/api/docs/index.md", "", []string{"docs"}},
+		{"code and helm", "src/api/helm/values.yaml\nsrc/api/Program.cs", "", []string{"build", "helm-lint"}},
+		{"build all", "src/api/docs/index.md", "test", []string{"build", "docs", "helm-lint"}},
+	}
+
+	for _, table := range tables {
+		cfg := &config.Config{
+			Input: config.InputConfig{
+				Projects: []config.Project{
+					{
+						Name:   "api",
+						Folder: "src/api",
+						Commands: []config.CommandGroup{
+							{Globs: []string{"**/*.cs"}, Cmd: "build"},
+							{Globs: []string{"docs/**"}, Cmd: "docs"},
+							{Globs: []string{"helm/**"}, Cmd: "helm-lint"},
+						},
+					},
+				},
+			},
+		}
+
+		affected := New(&models.App{Logger: logger}, cfg, logger)
+		affected.buildAll = table.buildAll
+
+		spawns := affected.getProjects(parser.ParseLines(table.files))
+
+		assert.Equal(t, 1, len(spawns), table.name)
+		assert.Equal(t, table.expected, spawns[0].GetCommands(), table.name)
+		assert.Equal(t, table.buildAll, spawns[0].BuildAll, table.name)
+	}
+}
+
+// TestGetProjectsReasons tests that the reasons each project was, or was not, selected are
+// recorded so that they can be shown by the explain command
+func TestGetProjectsReasons(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	tables := []struct {
+		name       string
+		project    string
+		options    config.Options
+		directives directives
+		expected   []string
+	}{
+		{"matched", "api", config.Options{}, directives{}, []string{"changed files match the project: src/api/main.go"}},
+		{"not matched", "lib", config.Options{}, directives{}, []string{"none of the changed files match the project"}},
+		{"dependency", "web", config.Options{}, directives{}, []string{"none of the changed files match the project", "web affected via api"}},
+		{"ignore option", "api", config.Options{Ignore: "api"}, directives{}, []string{"ignored by the ignore option"}},
+		{"ignore directive", "api", config.Options{}, directives{Ignore: []string{"api"}}, []string{"ignored by the ignore directive"}},
+		{"build directive", "lib", config.Options{}, directives{Build: []string{"lib"}}, []string{"built as requested by the build directive"}},
+		{"skip directive", "api", config.Options{}, directives{Skip: true}, []string{"not built as the skip directive has been set"}},
+		{"tags", "api", config.Options{SkipTags: "apps"}, directives{}, []string{"changed files match the project: src/api/main.go", "not selected as it has one of the tags apps"}},
+		{"name", "api", config.Options{Only: "web"}, directives{}, []string{"changed files match the project: src/api/main.go", "not selected as it has a name that does not match 'web'"}},
+	}
+
+	for _, table := range tables {
+		cfg := &config.Config{
+			Input: config.InputConfig{
+				Projects: []config.Project{
+					{Name: "lib", Folder: "libs/common", Patterns: []string{".*"}},
+					{Name: "api", Folder: "src/api", Patterns: []string{".*"}, Tags: []string{"apps"}},
+					{Name: "web", Folder: "src/web", Patterns: []string{".*"}, DependsOn: []string{"api"}},
+				},
+				Options: table.options,
+			},
+		}
+
+		affected := New(&models.App{Logger: logger}, cfg, logger)
+		affected.directives = table.directives
+		affected.getProjects(parser.ParseLines("src/api/main.go"))
+
+		assert.Equal(t, table.expected, affected.reasons[table.project], table.name)
+	}
+}
+
+// TestGetFilesFromPipe tests that the files piped to the command are parsed, and that
+// input that cannot be parsed is reported as an error rather than building nothing
+func TestGetFilesFromPipe(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+
+	tables := []struct {
+		name     string
+		input    string
+		expected []string
+		err      bool
+	}{
+		{"valid", `["src/api/main.go"]`, []string{"src/api/main.go"}, false},
+		{"malformed", `["src/api/main.go"`, nil, true},
+	}
+
+	stdin := os.Stdin
+	defer func() { os.Stdin = stdin }()
+
+	for _, table := range tables {
+		reader, writer, err := os.Pipe()
+		assert.NoError(t, err)
+
+		_, err = writer.WriteString(table.input)
+		assert.NoError(t, err)
+		writer.Close()
+
+		os.Stdin = reader
+
+		cfg := &config.Config{Input: config.InputConfig{InputFormat: config.InputFormatJSON}}
+		affected := New(&models.App{Logger: logger}, cfg, logger)
+
+		files, err := affected.getFiles()
+		reader.Close()
+
+		if table.err {
+			assert.Error(t, err, table.name)
+			continue
+		}
+
+		assert.NoError(t, err, table.name)
+		assert.Equal(t, table.expected, splitFiles(files), table.name)
+	}
+}
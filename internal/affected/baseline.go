@@ -0,0 +1,165 @@
+package affected
+
+import (
+	"github.com/amido/mrbuild/internal/config"
+	"github.com/amido/mrbuild/internal/git"
+	"github.com/amido/mrbuild/internal/models"
+	log "github.com/sirupsen/logrus"
+)
+
+// getMarkerFiles returns the files that have changed since the last successful build of
+// the project, and the commit of that build. If markers are not being used, the changes
+// did not come from git or the project does not have a marker, false is returned and the
+// project is compared against the branch
+func (a *Affected) getMarkerFiles(project config.Project) (string, []models.ChangedFile, bool) {
+
+	if !a.Config.Input.Baseline.Enabled() || a.from == "" || a.buildAll != "" {
+		return "", nil, false
+	}
+
+	marker, err := a.getMarker(project.Name)
+	if err != nil {
+		a.Logger.Warnf("Unable to read the last successful build of %s: %s", project.Name, err.Error())
+		return "", nil, false
+	}
+
+	if marker == "" {
+		a.Logger.Debugf("No last successful build has been recorded, comparing against the branch: %s", project.Name)
+		return "", nil, false
+	}
+
+	provider, err := a.getProvider()
+	if err != nil {
+		return "", nil, false
+	}
+
+	var files []models.ChangedFile
+	if a.to == "" {
+		files, err = provider.DiffWorktree(marker, a.Config.Input.Compare.Staged)
+	} else {
+		files, err = provider.DiffCommits(marker, a.to)
+	}
+
+	var local []models.ChangedFile
+	if err == nil {
+		local, err = a.getWorktreeFiles()
+	}
+
+	if err != nil {
+		a.Logger.Warnf("Unable to compare %s with its last successful build: %s", project.Name, err.Error())
+		return "", nil, false
+	}
+
+	files = mergeChanges(files, local)
+
+	a.Logger.WithFields(
+		log.Fields{
+			"project": project.Name,
+			"marker":  marker,
+			"count":   len(files),
+		},
+	).Info("Comparing project against last successful build")
+
+	return marker, files, true
+}
+
+// getMarker returns the SHA of the commit of the last successful build of the project,
+// or an empty string if one has not been recorded
+func (a *Affected) getMarker(project string) (string, error) {
+
+	baseline := a.Config.Input.Baseline
+
+	provider, err := a.getProvider()
+	if err != nil {
+		return "", err
+	}
+
+	if baseline.Marker == config.MarkerTag {
+		return provider.Tag(baseline.GetTagName(project))
+	}
+
+	// the notes are read once and the most recent commit that has a note containing
+	// the project is used
+	if a.notes == nil {
+		notes, err := provider.Notes(baseline.Prefix)
+		if err != nil {
+			return "", err
+		}
+
+		a.notes = append([]git.Note{}, notes...)
+	}
+
+	return findNote(a.notes, "", project), nil
+}
+
+// findNote returns the commit of the first note that contains the project, optionally
+// limited to the specified commit
+func findNote(notes []git.Note, commit string, project string) string {
+
+	for _, note := range notes {
+		if commit != "" && note.Commit != commit {
+			continue
+		}
+
+		for _, line := range note.Lines {
+			if line == project {
+				return note.Commit
+			}
+		}
+	}
+
+	return ""
+}
+
+// recordSuccess records that the project has been built successfully at HEAD, so that it
+// is compared against this commit in the next run
+// The builds are run concurrently so only one marker is written at a time
+func (a *Affected) recordSuccess(project string) {
+
+	baseline := a.Config.Input.Baseline
+	if !baseline.Enabled() {
+		return
+	}
+
+	a.markerLock.Lock()
+	defer a.markerLock.Unlock()
+
+	provider, err := a.getProvider()
+	if err == nil {
+		err = a.setMarker(provider, project)
+	}
+
+	fields := log.Fields{
+		"project": project,
+		"marker":  baseline.Marker,
+	}
+
+	if err != nil {
+		a.Logger.WithFields(fields).Warnf("Unable to record successful build: %s", err.Error())
+		return
+	}
+
+	a.Logger.WithFields(fields).Info("Recorded successful build")
+}
+
+// setMarker writes the marker for the project at HEAD
+// The note is not appended to if it already contains the project, e.g. the build has been rerun
+func (a *Affected) setMarker(provider git.Provider, project string) error {
+
+	baseline := a.Config.Input.Baseline
+
+	if baseline.Marker == config.MarkerTag {
+		return provider.SetTag(baseline.GetTagName(project), "HEAD")
+	}
+
+	head, err := provider.ResolveRef("HEAD")
+	if err != nil {
+		return err
+	}
+
+	if findNote(a.notes, head, project) != "" {
+		return nil
+	}
+
+	return provider.AppendNote(baseline.Prefix, "HEAD", project)
+}
@@ -0,0 +1,121 @@
+package affected
+
+import (
+	"testing"
+
+	"github.com/amido/mrbuild/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// newBaselineRepo creates a repository where web was last built successfully before
+// it was changed, and the latest commit only changes api
+func newBaselineRepo(t *testing.T) (*testRepo, string) {
+	repo := newTestRepo(t)
+
+	repo.Write("src/api/main.go", "package main")
+	repo.Write("src/web/index.js", "console.log()")
+	success := repo.Commit("add projects")
+
+	repo.Write("src/web/index.js", "console.log('web')")
+	repo.Commit("change web")
+
+	repo.Write("src/api/main.go", "package main\n\nfunc main() {}")
+	repo.Commit("change api")
+
+	return repo, success
+}
+
+// getBaselineProjects returns the names of the projects that are affected by the latest commit
+func getBaselineProjects(t *testing.T, repo *testRepo, provider string, marker string) []string {
+	affected := newRepoAffected(repo, config.InputConfig{
+		Compare:  config.Compare{Provider: provider, From: "HEAD~1"},
+		Baseline: config.Baseline{Marker: marker},
+		Projects: []config.Project{
+			{Name: "api", Folder: "src/api", Patterns: []string{".*\\.go"}},
+			{Name: "web", Folder: "src/web", Patterns: []string{".*\\.js"}},
+		},
+	})
+
+	if err := affected.Config.Input.Compare.Check(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := affected.Config.Input.Baseline.Check(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := affected.getGitFiles()
+	assert.NoError(t, err)
+
+	actual := []string{}
+	for _, spawn := range affected.getProjects(files) {
+		actual = append(actual, spawn.Name)
+	}
+
+	return actual
+}
+
+func TestGetProjectsBaselineTag(t *testing.T) {
+	for _, provider := range providers {
+		repo, success := newBaselineRepo(t)
+
+		assert.Equal(t, []string{"api"}, getBaselineProjects(t, repo, provider, config.MarkerNone), "%s: only the latest change should be built without markers", provider)
+
+		repo.Git("tag", "mrbuild/web/last-success", success)
+
+		assert.Equal(t, []string{"api", "web"}, getBaselineProjects(t, repo, provider, config.MarkerTag), "%s: web has changed since its last success", provider)
+
+		repo.Git("tag", "-f", "mrbuild/web/last-success", "HEAD")
+
+		assert.Equal(t, []string{"api"}, getBaselineProjects(t, repo, provider, config.MarkerTag), "%s: web has not changed since its last success", provider)
+	}
+}
+
+func TestGetProjectsBaselineNotes(t *testing.T) {
+	for _, provider := range providers {
+		repo, success := newBaselineRepo(t)
+
+		repo.Git("notes", "--ref=mrbuild", "append", "-m", "web", success)
+		repo.Git("notes", "--ref=mrbuild", "append", "-m", "docs", "HEAD")
+
+		assert.Equal(t, []string{"api", "web"}, getBaselineProjects(t, repo, provider, config.MarkerNotes), "%s: web has changed since its last success", provider)
+	}
+}
+
+func TestRecordSuccess(t *testing.T) {
+	for _, provider := range providers {
+		repo, _ := newBaselineRepo(t)
+		head := repo.Git("rev-parse", "HEAD")
+
+		affected := newRepoAffected(repo, config.InputConfig{
+			Compare:  config.Compare{Provider: provider},
+			Baseline: config.Baseline{Marker: config.MarkerTag},
+		})
+		assert.NoError(t, affected.Config.Input.Baseline.Check())
+
+		affected.recordSuccess("web")
+		affected.recordSuccess("web")
+
+		assert.Equal(t, head, repo.Git("rev-parse", "mrbuild/web/last-success"), "%s: the tag should point to HEAD", provider)
+	}
+
+	// notes can only be written using the git executable
+	repo, _ := newBaselineRepo(t)
+	repo.Git("config", "user.name", "mrbuild")
+	repo.Git("config", "user.email", "mrbuild@example.com")
+
+	affected := newRepoAffected(repo, config.InputConfig{
+		Baseline: config.Baseline{Marker: config.MarkerNotes},
+	})
+	assert.NoError(t, affected.Config.Input.Baseline.Check())
+
+	for _, project := range []string{"web", "api", "web"} {
+		_, err := affected.getMarker(project)
+		assert.NoError(t, err)
+
+		affected.recordSuccess(project)
+		affected.notes = nil
+	}
+
+	assert.Equal(t, "web\n\napi", repo.Git("notes", "--ref=mrbuild", "show", "HEAD"), "The note should contain each project once")
+}
@@ -0,0 +1,38 @@
+package affected
+
+import (
+	"github.com/amido/mrbuild/internal/models"
+)
+
+// mergeChanges combines the lists of changed files into one list
+// Files that appear in more than one list are only added once, the first status found is kept
+func mergeChanges(lists ...[]models.ChangedFile) []models.ChangedFile {
+
+	var merged []models.ChangedFile
+	seen := make(map[string]bool)
+
+	for _, list := range lists {
+		for _, file := range list {
+			if seen[file.Path] {
+				continue
+			}
+
+			seen[file.Path] = true
+			merged = append(merged, file)
+		}
+	}
+
+	return merged
+}
+
+// changesContain states if the list of changed files contains the path
+func changesContain(files []models.ChangedFile, path string) bool {
+
+	for _, file := range files {
+		if file.Path == path || file.OldPath == path {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,91 @@
+package affected
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/amido/mrbuild/internal/config"
+	"github.com/amido/mrbuild/internal/models"
+	"github.com/amido/mrbuild/internal/parser"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeChanges(t *testing.T) {
+	merged := mergeChanges(
+		parser.ParseLines("a.go\nb.go\n"),
+		[]models.ChangedFile{{Status: models.ChangeDeleted, Path: "b.go"}, {Status: models.ChangeAdded, Path: "c.go"}},
+	)
+
+	expected := []models.ChangedFile{
+		{Status: models.ChangeModified, Path: "a.go"},
+		{Status: models.ChangeModified, Path: "b.go"},
+		{Status: models.ChangeAdded, Path: "c.go"},
+	}
+
+	assert.Equal(t, expected, merged)
+}
+
+// TestGetProjectsRemovedAndRenamed checks that removed projects run the on_delete command
+// and that renames affect the projects that own the old and new paths
+func TestGetProjectsRemovedAndRenamed(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	// create the folders for the projects that still exist
+	dir := t.TempDir()
+	for _, folder := range []string{"src/api", "src/web", "src/new"} {
+		if err := os.MkdirAll(filepath.Join(dir, folder), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	projects := []config.Project{
+		{Name: "api", Folder: "src/api", Patterns: []string{".*"}, Build: config.Build{Cmd: "echo api"}},
+		{Name: "infra", Folder: "src/infra", Patterns: []string{".*"}, Build: config.Build{Cmd: "echo build"}, OnDelete: "echo destroy"},
+		{Name: "legacy", Folder: "src/legacy", Patterns: []string{".*"}, Build: config.Build{Cmd: "echo legacy"}},
+		{Name: "old", Folder: "src/old", Patterns: []string{".*"}, Build: config.Build{Cmd: "echo old", Folder: "."}, OnDelete: "echo remove old"},
+		{Name: "new", Folder: "src/new", Patterns: []string{".*"}, Build: config.Build{Cmd: "echo new"}},
+		{Name: "web", Folder: "src/web", Patterns: []string{".*"}, Build: config.Build{Cmd: "echo web"}, OnDelete: "echo destroy web"},
+	}
+
+	cfg := &config.Config{
+		Input: config.InputConfig{
+			Projects:  projects,
+			Directory: config.Directory{WorkingDir: dir},
+		},
+	}
+
+	affected := New(&models.App{Logger: logger}, cfg, logger)
+
+	files := []models.ChangedFile{
+		{Status: models.ChangeModified, Path: "src/api/main.go"},
+		{Status: models.ChangeDeleted, Path: "src/infra/main.tf"},
+		{Status: models.ChangeDeleted, Path: "src/legacy/main.go"},
+		{Status: models.ChangeRenamed, Path: "src/new/main.go", OldPath: "src/old/main.go"},
+		{Status: models.ChangeDeleted, Path: "src/web/unused.js"},
+	}
+
+	spawns := affected.getProjects(files)
+
+	commands := make(map[string]string)
+	deleted := make(map[string]bool)
+	for _, spawn := range spawns {
+		commands[spawn.Name] = spawn.Command
+		deleted[spawn.Name] = spawn.Deleted
+	}
+
+	expected := map[string]string{
+		"api":   "echo api",
+		"infra": "echo destroy",
+		"old":   "echo remove old",
+		"new":   "echo new",
+		"web":   "echo web",
+	}
+
+	assert.Equal(t, expected, commands, "Removed projects should run the on_delete command and projects without one should be skipped")
+	assert.True(t, deleted["infra"])
+	assert.True(t, deleted["old"])
+	assert.False(t, deleted["web"], "Deleting a file in a project that still exists is not a removal")
+}
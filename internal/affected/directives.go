@@ -0,0 +1,247 @@
+package affected
+
+import (
+	"encoding/json"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/amido/mrbuild/internal/util"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// directiveSkip states that no projects should be built
+	directiveSkip = "skip mrbuild"
+
+	// directiveBuildAll states that all projects should be built
+	directiveBuildAll = "build-all"
+
+	// directiveBuild states the projects that should be built, regardless of the changes
+	directiveBuild = "build"
+
+	// directiveIgnore states the projects that should not be built
+	directiveIgnore = "ignore"
+)
+
+// directivePattern matches the directives in a commit message, e.g. [skip mrbuild],
+// [build-all], [build: api,web] or [ignore: docs]
+var directivePattern = regexp.MustCompile(`(?i)\[\s*(skip mrbuild|build-all|(build|ignore)\s*:\s*([^\]]*?))\s*\]`)
+
+// directives holds the instructions for the run that have been set in the commit
+// message or the labels of the pull request
+type directives struct {
+	Skip     bool
+	BuildAll bool
+	Build    []string // Projects that should be built regardless of the changes
+	Ignore   []string // Projects that should not be built
+}
+
+// githubEvent holds the parts of the GitHub event payload that can contain directives
+type githubEvent struct {
+	HeadCommit *struct {
+		Message string `json:"message"`
+	} `json:"head_commit"`
+	PullRequest *struct {
+		Head struct {
+			Sha string `json:"sha"`
+		} `json:"head"`
+		Labels []struct {
+			Name string `json:"name"`
+		} `json:"labels"`
+	} `json:"pull_request"`
+}
+
+// applyDirectives reads the directives and applies those that affect the whole run
+// Projects that are ignored by a directive are kept apart from the ignore option, as the
+// names in a directive are matched exactly rather than as regular expressions
+func (a *Affected) applyDirectives() {
+
+	// the commit message is only read if the changes are read from git, as otherwise
+	// there may not be a repository
+	a.directives = a.getDirectives(a.Config.Input.Datafile == "" && !a.Config.Input.Snapshot.Enabled && !util.IsInputFromPipe())
+
+	if a.directives.BuildAll && a.buildAll == "" {
+		a.buildAll = "build-all directive"
+	}
+}
+
+// getDirectives reads the directives from the message of the HEAD commit, if requested, and
+// from the GitHub event payload, if one has been detected. Each directive that is found is written to the log
+// The HEAD of a pull request build is often a merge commit that has been created by the CI/CD
+// system, so the message of the head commit of the pull request is read as well
+func (a *Affected) getDirectives(commit bool) directives {
+
+	var result directives
+	var event *githubEvent
+	var err error
+
+	if a.Config.Input.Options.NoDirectives {
+		a.Logger.Debug("Directives have been disabled")
+		return result
+	}
+
+	// the message of the head commit and the labels of the pull request are in the GitHub event
+	if path := a.Config.CI.EventPath; path != "" {
+		event, err = readGitHubEvent(path)
+		if err != nil {
+			a.Logger.Warnf("Unable to read GitHub event for directives: %s", err.Error())
+			event = nil
+		}
+	}
+
+	// the messages that have been read, so that a message is only parsed once
+	messages := make(map[string]bool)
+
+	parse := func(message string, source string) {
+		if message = strings.TrimSpace(message); message != "" && !messages[message] {
+			messages[message] = true
+			result.parse(message, source, a.Logger)
+		}
+	}
+
+	if commit {
+		message, err := a.getCommitMessage("HEAD")
+		if err == nil {
+			parse(message, "commit message")
+		} else {
+			a.Logger.Warnf("Unable to read commit message for directives: %s", err.Error())
+		}
+
+		if ref := a.getPullRequestHead(event); ref != "" {
+			message, err := a.getCommitMessage(ref)
+			if err == nil {
+				parse(message, "pull request head commit")
+			} else {
+				a.Logger.Warnf("Unable to read the head commit of the pull request for directives: %s", err.Error())
+			}
+		}
+	}
+
+	if event != nil {
+
+		// the head commit is the commit that has been checked out on a push
+		if event.HeadCommit != nil {
+			parse(event.HeadCommit.Message, "github head commit")
+		}
+
+		if event.PullRequest != nil {
+			for _, label := range event.PullRequest.Labels {
+
+				// labels are written without the brackets
+				result.parse("["+label.Name+"]", "github label", a.Logger)
+			}
+		}
+	}
+
+	return result
+}
+
+// getPullRequestHead returns the ref of the head commit of the pull request that is being built
+// This is the SHA in the GitHub event or, for other CI/CD systems, the second parent of HEAD if
+// it is the merge commit that has been created for the pull request
+func (a *Affected) getPullRequestHead(event *githubEvent) string {
+
+	if event != nil && event.PullRequest != nil && event.PullRequest.Head.Sha != "" {
+		return event.PullRequest.Head.Sha
+	}
+
+	if a.Config.CI.PullRequest == "" {
+		return ""
+	}
+
+	if _, err := a.resolveRef("HEAD^2"); err != nil {
+		return ""
+	}
+
+	return "HEAD^2"
+}
+
+// getCommitMessage returns the message of the commit that the ref points to
+func (a *Affected) getCommitMessage(ref string) (string, error) {
+
+	provider, err := a.getProvider()
+	if err != nil {
+		return "", err
+	}
+
+	return provider.CommitMessage(ref)
+}
+
+// parse adds the directives that are found in the text
+func (d *directives) parse(text string, source string, logger *log.Logger) {
+
+	for _, match := range directivePattern.FindAllStringSubmatch(text, -1) {
+
+		directive := strings.ToLower(match[1])
+		fields := log.Fields{
+			"directive": strings.TrimSpace(match[0]),
+			"source":    source,
+		}
+
+		switch {
+		case directive == directiveSkip:
+			d.Skip = true
+		case directive == directiveBuildAll:
+			d.BuildAll = true
+		case strings.EqualFold(match[2], directiveBuild):
+			d.Build = append(d.Build, splitProjects(match[3])...)
+		case strings.EqualFold(match[2], directiveIgnore):
+			d.Ignore = append(d.Ignore, splitProjects(match[3])...)
+		}
+
+		logger.WithFields(fields).Info("Applying directive")
+	}
+}
+
+// isBuilt states if the project has been requested by a build directive
+func (d *directives) isBuilt(project string) bool {
+	return containsProject(d.Build, project)
+}
+
+// isIgnored states if the project has been ignored by an ignore directive
+func (d *directives) isIgnored(project string) bool {
+	return containsProject(d.Ignore, project)
+}
+
+// containsProject states if the name of the project is in the list. The directives come
+// from commit messages and labels, so the names are compared exactly rather than being
+// used as regular expressions
+func containsProject(projects []string, project string) bool {
+
+	for _, item := range projects {
+		if strings.EqualFold(item, project) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// splitProjects splits the comma delimited list of projects
+func splitProjects(list string) []string {
+
+	var projects []string
+
+	for _, project := range strings.Split(list, ",") {
+		if project = strings.TrimSpace(project); project != "" {
+			projects = append(projects, project)
+		}
+	}
+
+	return projects
+}
+
+// readGitHubEvent reads the event payload that GitHub Actions writes for the workflow
+func readGitHubEvent(path string) (*githubEvent, error) {
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	event := &githubEvent{}
+	err = json.Unmarshal(data, event)
+
+	return event, err
+}
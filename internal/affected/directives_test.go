@@ -0,0 +1,218 @@
+package affected
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/amido/mrbuild/internal/ci"
+	"github.com/amido/mrbuild/internal/config"
+	"github.com/amido/mrbuild/internal/models"
+	"github.com/amido/mrbuild/internal/parser"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDirectives(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	tables := []struct {
+		text     string
+		expected directives
+	}{
+		{"Fix typo in readme", directives{}},
+		{"Update docs [skip mrbuild]", directives{Skip: true}},
+		{"Update docs [SKIP MRBUILD]", directives{Skip: true}},
+		{"Bump versions\n\n[build-all]", directives{BuildAll: true}},
+		{"Fix api [build: api, web ]", directives{Build: []string{"api", "web"}}},
+		{"Fix api [build:api] [ignore: infra,docs]", directives{Build: []string{"api"}, Ignore: []string{"infra", "docs"}}},
+		{"Fix api [build: ]", directives{}},
+	}
+
+	for _, table := range tables {
+		actual := directives{}
+		actual.parse(table.text, "test", logger)
+
+		assert.Equal(t, table.expected, actual, table.text)
+	}
+}
+
+// TestGetDirectives checks that the directives are read from the commit message and
+// the labels in the GitHub event
+func TestGetDirectives(t *testing.T) {
+
+	event := filepath.Join(t.TempDir(), "event.json")
+	err := os.WriteFile(event, []byte(`{
+		"head_commit": {"message": "Update [build: docs]"},
+		"pull_request": {"labels": [{"name": "bug"}, {"name": "ignore: infra"}]}
+	}`), 0644)
+	assert.NoError(t, err)
+
+	for _, provider := range providers {
+		repo := newTestRepo(t)
+		repo.Write("src/api/main.go", "package main")
+		repo.Commit("Change api\n\n[build: web]")
+
+		affected := newRepoAffected(repo, config.InputConfig{
+			Compare: config.Compare{Provider: provider},
+		})
+		affected.Config.CI = ci.Context{System: ci.GitHub, EventPath: event}
+
+		expected := directives{
+			Build:  []string{"web", "docs"},
+			Ignore: []string{"infra"},
+		}
+
+		assert.Equal(t, expected, affected.getDirectives(true), provider)
+	}
+}
+
+// TestGetDirectivesPullRequest checks that the directives are read from the head commit of a
+// pull request when HEAD is the merge commit that has been created by the CI/CD system
+func TestGetDirectivesPullRequest(t *testing.T) {
+
+	for _, provider := range providers {
+		repo := newTestRepo(t)
+
+		repo.Git("checkout", "-q", "-b", "feature")
+		repo.Write("src/api/main.go", "package main")
+		head := repo.Commit("Change api\n\n[build: web]")
+
+		repo.Git("checkout", "-q", "main")
+		repo.Git("merge", "-q", "--no-ff", "-m", "Merge feature into main", "feature")
+
+		event := filepath.Join(t.TempDir(), "event.json")
+		err := os.WriteFile(event, []byte(`{"pull_request": {"head": {"sha": "`+head+`"}, "labels": []}}`), 0644)
+		assert.NoError(t, err)
+
+		tables := []struct {
+			name     string
+			ci       ci.Context
+			expected directives
+		}{
+			{"github event", ci.Context{System: ci.GitHub, EventPath: event}, directives{Build: []string{"web"}}},
+			{"merge commit", ci.Context{System: ci.GitLab, PullRequest: "12"}, directives{Build: []string{"web"}}},
+			{"not a pull request", ci.Context{}, directives{}},
+		}
+
+		for _, table := range tables {
+			affected := newRepoAffected(repo, config.InputConfig{
+				Compare: config.Compare{Provider: provider},
+			})
+			affected.Config.CI = table.ci
+
+			assert.Equal(t, table.expected, affected.getDirectives(true), provider+"/"+table.name)
+		}
+	}
+}
+
+// TestApplyDirectives checks that the ignore directive is combined with the ignore option
+// and that the build-all directive builds every project
+func TestApplyDirectives(t *testing.T) {
+
+	event := filepath.Join(t.TempDir(), "event.json")
+	err := os.WriteFile(event, []byte(`{"pull_request": {"labels": [{"name": "build-all"}, {"name": "ignore: web"}]}}`), 0644)
+	assert.NoError(t, err)
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	cfg := &config.Config{
+		Input: config.InputConfig{
+			Datafile: "changes.txt",
+			Options:  config.Options{Ignore: "infra"},
+			Projects: []config.Project{
+				{Name: "api", Folder: "src/api", Patterns: []string{".*\\.go"}},
+				{Name: "web", Folder: "src/web", Patterns: []string{".*\\.js"}},
+				{Name: "infra", Folder: "src/infra", Patterns: []string{".*\\.tf"}},
+			},
+		},
+		CI: ci.Context{System: ci.GitHub, EventPath: event},
+	}
+
+	affected := New(&models.App{Logger: logger}, cfg, logger)
+	affected.applyDirectives()
+
+	assert.Equal(t, "infra", cfg.Input.Options.Ignore, "The ignore option should not be changed by the directive")
+	assert.Equal(t, []string{"web"}, affected.directives.Ignore)
+
+	spawns := affected.getProjects(nil)
+	assert.Len(t, spawns, 1)
+	assert.Equal(t, "api", spawns[0].Name)
+
+	// directives are not read if they have been disabled
+	cfg.Input.Options = config.Options{NoDirectives: true}
+	affected = New(&models.App{Logger: logger}, cfg, logger)
+	affected.applyDirectives()
+
+	assert.Equal(t, directives{}, affected.directives)
+	assert.Equal(t, "", cfg.Input.Options.Ignore)
+}
+
+// TestGetProjectsDirectivesExactNames checks that the names in the directives are compared
+// exactly, as they come from commit messages and labels rather than the configuration
+func TestGetProjectsDirectivesExactNames(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	tables := []struct {
+		name       string
+		directives directives
+		expected   []string
+	}{
+		{"build prefix", directives{Build: []string{"api-gateway"}}, []string{"web"}},
+		{"build case", directives{Build: []string{"API"}}, []string{"api", "web"}},
+		{"build metacharacters", directives{Build: []string{"api(", "[web"}}, []string{"web"}},
+		{"ignore prefix", directives{Ignore: []string{"a"}}, []string{"web"}},
+		{"ignore any character", directives{Ignore: []string{"."}}, []string{"web"}},
+		{"ignore metacharacters", directives{Ignore: []string{"web("}}, []string{"web"}},
+		{"ignore exact", directives{Ignore: []string{"web"}}, []string{}},
+	}
+
+	for _, table := range tables {
+		cfg := &config.Config{
+			Input: config.InputConfig{
+				Projects: []config.Project{
+					{Name: "api", Folder: "src/api", Patterns: []string{".*\\.go"}},
+					{Name: "web", Folder: "src/web", Patterns: []string{".*\\.js"}},
+				},
+			},
+		}
+
+		affected := New(&models.App{Logger: logger}, cfg, logger)
+		affected.directives = table.directives
+
+		actual := []string{}
+		for _, spawn := range affected.getProjects(parser.ParseLines("src/web/app.js")) {
+			actual = append(actual, spawn.Name)
+		}
+
+		assert.Equal(t, table.expected, actual, table.name)
+	}
+}
+
+func TestGetProjectsDirectives(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	cfg := &config.Config{
+		Input: config.InputConfig{
+			Projects: []config.Project{
+				{Name: "api", Folder: "src/api", Patterns: []string{".*\\.go"}},
+				{Name: "web", Folder: "src/web", Patterns: []string{".*\\.js"}},
+			},
+		},
+	}
+
+	files := parser.ParseLines("src/api/main.go")
+
+	affected := New(&models.App{Logger: logger}, cfg, logger)
+	affected.directives = directives{Build: []string{"web"}}
+
+	assert.Len(t, affected.getProjects(files), 2, "The project in the build directive should be built")
+
+	affected.directives = directives{Skip: true, Build: []string{"web"}}
+
+	assert.Len(t, affected.getProjects(files), 0, "No projects should be built when skipping")
+}
@@ -0,0 +1,53 @@
+package affected
+
+import (
+	"fmt"
+)
+
+// Explanation states why a project was, or was not, selected to be built
+type Explanation struct {
+	Project  string
+	Selected bool     // State if the project would be built
+	Files    []string // Changed files that matched the project, even if it was not selected
+	Commands []string // Commands that would be run to build the project
+	Reasons  []string // Each decision that was made about the project, in the order it was made
+}
+
+// Explain detects the changes in the same way as Run, but rather than building the affected
+// projects it returns the explanation of why the named project was, or was not, selected
+func (a *Affected) Explain(name string) (*Explanation, error) {
+
+	spawns, err := a.analyse()
+	if err != nil {
+		return nil, err
+	}
+
+	explanation := &Explanation{
+		Project: name,
+		Files:   a.matched[name],
+		Reasons: a.reasons[name],
+	}
+
+	found := false
+	for _, project := range a.Config.Input.Projects {
+		if project.Name == name {
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("unknown project %s", name)
+	}
+
+	for _, spawn := range spawns {
+		if spawn.Name != name {
+			continue
+		}
+
+		explanation.Selected = true
+		explanation.Commands = spawn.GetCommands()
+	}
+
+	return explanation, nil
+}
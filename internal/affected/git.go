@@ -0,0 +1,403 @@
+package affected
+
+import (
+	"fmt"
+
+	"github.com/amido/mrbuild/internal/config"
+	"github.com/amido/mrbuild/internal/git"
+	"github.com/amido/mrbuild/internal/models"
+	log "github.com/sirupsen/logrus"
+)
+
+// getGitFiles uses the git provider to get the list of files that have changed
+// The comparison that is performed is based on the compare mode in the configuration
+// Renames are detected so that the status of each file, and its previous path, is known
+func (a *Affected) getGitFiles() ([]models.ChangedFile, error) {
+
+	var files []models.ChangedFile
+
+	provider, err := a.getProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	compare := a.Config.Input.Compare
+
+	// determine the commits to compare, if there is no commit at the end of the
+	// comparison then the working tree is used
+	from, to, err := a.getComparison()
+	if err != nil || a.buildAll != "" {
+		return nil, err
+	}
+
+	a.from, a.to = from, to
+
+	// when only staged changes are required the comparison is made with the index so
+	// that the unstaged changes in the working tree are not included
+	if to == "" {
+		files, err = provider.DiffWorktree(from, compare.Staged)
+	} else {
+		files, err = provider.DiffCommits(from, to)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// add the files from the working tree that have been requested
+	local, err := a.getWorktreeFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	files = mergeChanges(files, local)
+
+	// add the files that have changed in any submodules that have been updated
+	if compare.Submodules {
+		files = a.getSubmoduleFiles(provider, files, from, to, "")
+	}
+
+	return files, nil
+}
+
+// getComparison returns the commits at the start and end of the comparison
+// If the commits cannot be found and the repository is a shallow clone, the history is
+// deepened until they can be found. If they still cannot be found the shallow fallback
+// policy is applied, which either returns an error or states that all projects should be built
+func (a *Affected) getComparison() (string, string, error) {
+
+	from, to, err := a.resolveComparison()
+	if err == nil {
+		return from, to, err
+	}
+
+	provider, perr := a.getProvider()
+	if perr != nil {
+		return "", "", perr
+	}
+
+	shallow, serr := provider.IsShallow()
+	if serr != nil || !shallow {
+		return "", "", err
+	}
+
+	settings := a.Config.Input.Shallow
+
+	a.Logger.WithFields(
+		log.Fields{
+			"error": err.Error(),
+		},
+	).Warn("Repository is a shallow clone and the commits to compare cannot be found")
+
+	// deepen the history a step at a time until the commits can be found
+	for depth := settings.Deepen; settings.Deepen > 0 && depth <= settings.Max; depth += settings.Deepen {
+
+		a.Logger.WithFields(
+			log.Fields{
+				"deepen": settings.Deepen,
+				"total":  depth,
+				"max":    settings.Max,
+			},
+		).Info("Deepening shallow clone")
+
+		if derr := provider.Deepen(settings.Deepen); derr != nil {
+			a.Logger.Warnf("Unable to deepen shallow clone: %s", derr.Error())
+			break
+		}
+
+		from, to, err = a.resolveComparison()
+		if err == nil {
+			return from, to, err
+		}
+	}
+
+	if settings.Fallback == config.ShallowFallbackAll {
+		a.buildAll = "changes cannot be determined in shallow clone"
+		a.Logger.WithFields(
+			log.Fields{
+				"fallback": settings.Fallback,
+			},
+		).Warn("Unable to determine changes in shallow clone, all projects will be built")
+
+		return "", "", nil
+	}
+
+	a.Logger.WithFields(
+		log.Fields{
+			"fallback": settings.Fallback,
+		},
+	).Error("Unable to determine changes in shallow clone")
+
+	return "", "", fmt.Errorf("unable to determine changes in shallow clone, set a greater depth or use the '%s' fallback: %s", config.ShallowFallbackAll, err.Error())
+}
+
+// resolveComparison returns the commits at the start and end of the comparison, according
+// to the compare mode. The commit at the end is empty if the working tree should be used
+func (a *Affected) resolveComparison() (string, string, error) {
+
+	branch := a.getBranch()
+
+	switch a.Config.Input.Compare.Mode {
+	case config.CompareModeTip:
+		sha, err := a.resolveRef(branch)
+		return sha, "", err
+
+	case config.CompareModeRange:
+		return a.getRange()
+	}
+
+	// find the commit that the current branch was created from so that changes
+	// made on the branch since then are not included
+	base, err := a.getMergeBase(branch)
+
+	return base, "", err
+}
+
+// getWorktreeFiles returns the changes in the working tree, according to the options
+// that have been set. Untracked files are treated as having been added
+// The merge-base and tip modes already compare with the working tree, so including the
+// working tree only adds changes in the range mode
+func (a *Affected) getWorktreeFiles() ([]models.ChangedFile, error) {
+
+	var result []models.ChangedFile
+
+	compare := a.Config.Input.Compare
+
+	provider, err := a.getProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	if compare.Worktree || compare.Staged {
+		staged, err := provider.DiffWorktree("HEAD", true)
+		if err != nil {
+			return nil, err
+		}
+
+		result = mergeChanges(result, staged)
+	}
+
+	if compare.Worktree {
+		unstaged, err := provider.Unstaged()
+		if err != nil {
+			return nil, err
+		}
+
+		result = mergeChanges(result, unstaged)
+	}
+
+	if compare.Untracked {
+		untracked, err := provider.Untracked()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, path := range untracked {
+			result = mergeChanges(result, []models.ChangedFile{{Status: models.ChangeAdded, Path: path}})
+		}
+	}
+
+	return result, nil
+}
+
+// getSubmoduleFiles adds the files that have changed in each of the submodules that have
+// been updated, with the path of the submodule as a prefix. Nested submodules are also checked
+// Submodules that cannot be read, e.g. they have not been initialised, are skipped
+func (a *Affected) getSubmoduleFiles(provider git.Provider, files []models.ChangedFile, from string, to string, prefix string) []models.ChangedFile {
+
+	submodules, err := provider.Submodules()
+	if err != nil {
+		a.Logger.Warnf("Unable to list submodules: %s", err.Error())
+		return files
+	}
+
+	for _, path := range submodules {
+
+		if !changesContain(files, prefix+path) {
+			continue
+		}
+
+		submodule, oldCommit, newCommit, err := a.getSubmoduleCommits(provider, path, from, to)
+
+		var inner []models.ChangedFile
+		if err == nil {
+			inner, err = submodule.DiffCommits(oldCommit, newCommit)
+		}
+
+		fields := log.Fields{
+			"submodule": prefix + path,
+			"from":      oldCommit,
+			"to":        newCommit,
+		}
+
+		if err != nil {
+			a.Logger.WithFields(fields).Warnf("Unable to get changes from submodule: %s", err.Error())
+			continue
+		}
+
+		// prefix the files with the path to the submodule
+		for i := range inner {
+			inner[i].Path = fmt.Sprintf("%s%s/%s", prefix, path, inner[i].Path)
+			if inner[i].OldPath != "" {
+				inner[i].OldPath = fmt.Sprintf("%s%s/%s", prefix, path, inner[i].OldPath)
+			}
+		}
+
+		fields["count"] = len(inner)
+		a.Logger.WithFields(fields).Info("Adding changes from submodule")
+
+		files = mergeChanges(files, inner)
+		files = a.getSubmoduleFiles(submodule, files, oldCommit, newCommit, fmt.Sprintf("%s%s/", prefix, path))
+	}
+
+	return files
+}
+
+// getSubmoduleCommits returns the provider for the submodule and the commits that it
+// points to at each end of the comparison
+func (a *Affected) getSubmoduleCommits(provider git.Provider, path string, from string, to string) (git.Provider, string, string, error) {
+
+	oldCommit, err := provider.SubmoduleCommit(from, path)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	newCommit, err := provider.SubmoduleCommit(to, path)
+	if err != nil {
+		return nil, oldCommit, "", err
+	}
+
+	if oldCommit == "" || newCommit == "" {
+		return nil, oldCommit, newCommit, fmt.Errorf("submodule has been added or removed")
+	}
+
+	submodule, err := provider.Submodule(path)
+
+	return submodule, oldCommit, newCommit, err
+}
+
+// getMergeBase returns the SHA of the best common ancestor of HEAD and the specified ref
+func (a *Affected) getMergeBase(ref string) (string, error) {
+
+	provider, err := a.getProvider()
+	if err != nil {
+		return "", err
+	}
+
+	base, err := provider.MergeBase(ref)
+	if err != nil {
+		return "", fmt.Errorf("unable to find merge-base of HEAD and %s: %s", ref, err.Error())
+	}
+
+	a.Logger.WithFields(
+		log.Fields{
+			"branch": ref,
+			"base":   base,
+		},
+	).Info("Comparing changes against merge-base")
+
+	return base, nil
+}
+
+// getRange returns the SHAs of the commits at the start and end of the range to compare
+// If refs have not been set the range is from the branch to HEAD
+func (a *Affected) getRange() (string, string, error) {
+
+	compare := a.Config.Input.Compare
+
+	from := compare.From
+	if compare.Since != "" {
+		from = compare.Since
+	}
+
+	if from == "" {
+		from = a.getBranch()
+	}
+
+	to := compare.To
+	if to == "" {
+		to = "HEAD"
+	}
+
+	fromSha, err := a.resolveRef(from)
+	if err != nil {
+		return "", "", err
+	}
+
+	toSha, err := a.resolveRef(to)
+	if err != nil {
+		return "", "", err
+	}
+
+	a.Logger.WithFields(
+		log.Fields{
+			"from":    from,
+			"fromSha": fromSha,
+			"to":      to,
+			"toSha":   toSha,
+		},
+	).Info("Comparing changes in commit range")
+
+	return fromSha, toSha, nil
+}
+
+// getBranch returns the branch that the changes are compared against. If the branch has
+// been detected, rather than set, and it does not exist locally, which is common in the
+// checkout made by a CI/CD system, the branch of the origin remote is used instead
+func (a *Affected) getBranch() string {
+
+	branch := a.Config.Input.Branch
+	if !a.Config.BranchDetected {
+		return branch
+	}
+
+	if _, err := a.resolveRef(branch); err == nil {
+		return branch
+	}
+
+	remote := "origin/" + branch
+	if _, err := a.resolveRef(remote); err != nil {
+		return branch
+	}
+
+	a.Logger.WithFields(
+		log.Fields{
+			"branch": branch,
+			"remote": remote,
+		},
+	).Info("Branch does not exist locally, comparing against the remote branch")
+
+	return remote
+}
+
+// resolveRef returns the SHA of the commit that the ref points to
+func (a *Affected) resolveRef(ref string) (string, error) {
+
+	provider, err := a.getProvider()
+	if err != nil {
+		return "", err
+	}
+
+	sha, err := provider.ResolveRef(ref)
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve ref '%s': %s", ref, err.Error())
+	}
+
+	return sha, nil
+}
+
+// getProvider returns the git provider that has been configured, creating it
+// the first time that it is requested
+func (a *Affected) getProvider() (git.Provider, error) {
+
+	if a.Provider == nil {
+		provider, err := git.NewProvider(a.Config, a.Logger)
+		if err != nil {
+			return nil, err
+		}
+
+		a.Provider = provider
+	}
+
+	return a.Provider, nil
+}
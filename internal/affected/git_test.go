@@ -0,0 +1,518 @@
+package affected
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/amido/mrbuild/internal/config"
+	"github.com/amido/mrbuild/internal/models"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// testRepo is a temporary git repository that tests can make changes in
+type testRepo struct {
+	t   *testing.T
+	Dir string
+}
+
+// newTestRepo creates a git repository in a temporary directory with an
+// initial commit on the main branch
+func newTestRepo(t *testing.T) *testRepo {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+
+	repo := &testRepo{t: t, Dir: t.TempDir()}
+	repo.Git("init", "-q", "-b", "main")
+	repo.Write("README.md", "readme")
+	repo.Commit("initial commit")
+
+	return repo
+}
+
+// Git runs the git command in the repository and returns the output
+func (r *testRepo) Git(args ...string) string {
+	args = append([]string{"-c", "user.name=mrbuild", "-c", "user.email=mrbuild@example.com", "-c", "commit.gpgsign=false", "-c", "protocol.file.allow=always"}, args...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.Dir
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		r.t.Fatalf("git %s failed: %s\n%s", strings.Join(args, " "), err.Error(), out)
+	}
+
+	return strings.TrimSpace(string(out))
+}
+
+// Write creates or overwrites the file in the repository
+func (r *testRepo) Write(path string, content string) {
+	full := filepath.Join(r.Dir, path)
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		r.t.Fatal(err)
+	}
+
+	if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+// Commit stages all the changes and commits them
+func (r *testRepo) Commit(message string) string {
+	r.Git("add", "-A")
+	r.Git("commit", "-q", "-m", message)
+
+	return r.Git("rev-parse", "HEAD")
+}
+
+// newRepoAffected returns an Affected object that is configured to work
+// in the test repository
+func newRepoAffected(repo *testRepo, input config.InputConfig) *Affected {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	cfg := &config.Config{Input: input}
+	cfg.Input.Directory.WorkingDir = repo.Dir
+
+	if err := cfg.Input.Compare.Check(); err != nil {
+		repo.t.Fatal(err)
+	}
+
+	return New(&models.App{Logger: logger}, cfg, logger)
+}
+
+// providers is the list of git providers that the tests are run against, so that
+// the results from each can be compared
+var providers = []string{config.GitProviderExec, config.GitProviderNative}
+
+// splitFiles returns a sorted slice of the paths of the changed files
+func splitFiles(files []models.ChangedFile) []string {
+	result := []string{}
+
+	for _, file := range files {
+		result = append(result, file.Path)
+	}
+
+	sort.Strings(result)
+
+	return result
+}
+
+// TestGetGitFilesCompareModes checks that the compare mode determines which
+// commits the changes are measured against
+func TestGetGitFilesCompareModes(t *testing.T) {
+	repo := newTestRepo(t)
+
+	// create a feature branch and then move main on
+	repo.Git("checkout", "-q", "-b", "feature")
+	repo.Write("src/api/main.go", "package main")
+	repo.Commit("feature change")
+
+	repo.Git("checkout", "-q", "main")
+	repo.Write("src/web/index.js", "console.log()")
+	repo.Commit("main change")
+
+	repo.Git("checkout", "-q", "feature")
+
+	// add a change to the working tree that has not been committed
+	repo.Write("src/api/main.go", "package main\n")
+
+	testCases := []struct {
+		mode     string
+		expected []string
+	}{
+		{config.CompareModeMergeBase, []string{"src/api/main.go"}},
+		{config.CompareModeTip, []string{"src/api/main.go", "src/web/index.js"}},
+		{config.CompareModeRange, []string{"src/api/main.go", "src/web/index.js"}},
+	}
+
+	for _, provider := range providers {
+		for _, testCase := range testCases {
+			t.Run(provider+"/"+testCase.mode, func(t *testing.T) {
+				a := newRepoAffected(repo, config.InputConfig{
+					Branch:  "main",
+					Compare: config.Compare{Mode: testCase.mode, Provider: provider},
+				})
+
+				files, err := a.getGitFiles()
+
+				assert.NoError(t, err)
+				assert.Equal(t, testCase.expected, splitFiles(files))
+			})
+		}
+	}
+}
+
+// TestGetGitFilesWorktreeOptions checks that changes in the working tree are
+// merged with the changes compared against the branch
+func TestGetGitFilesWorktreeOptions(t *testing.T) {
+	repo := newTestRepo(t)
+
+	repo.Write("src/api/tracked.go", "package api")
+	repo.Commit("add tracked file")
+
+	repo.Git("checkout", "-q", "-b", "feature")
+	repo.Write("src/api/committed.go", "package api")
+	repo.Commit("feature change")
+
+	// stage one change, leave one unstaged and add an untracked file
+	repo.Write("src/api/staged.go", "package api")
+	repo.Git("add", "src/api/staged.go")
+	repo.Write("src/api/tracked.go", "package api\n")
+	repo.Write("src/web/untracked.js", "console.log()")
+
+	testCases := []struct {
+		name     string
+		compare  config.Compare
+		expected []string
+	}{
+		{
+			"committed only",
+			config.Compare{Mode: config.CompareModeRange},
+			[]string{"src/api/committed.go"},
+		},
+		{
+			"include worktree",
+			config.Compare{Mode: config.CompareModeRange, Worktree: true},
+			[]string{"src/api/committed.go", "src/api/staged.go", "src/api/tracked.go"},
+		},
+		{
+			"staged only",
+			config.Compare{Mode: config.CompareModeRange, Staged: true},
+			[]string{"src/api/committed.go", "src/api/staged.go"},
+		},
+		{
+			"merge-base includes the worktree",
+			config.Compare{Mode: config.CompareModeMergeBase},
+			[]string{"src/api/committed.go", "src/api/staged.go", "src/api/tracked.go"},
+		},
+		{
+			"include worktree against merge-base",
+			config.Compare{Mode: config.CompareModeMergeBase, Worktree: true},
+			[]string{"src/api/committed.go", "src/api/staged.go", "src/api/tracked.go"},
+		},
+		{
+			"staged only against merge-base",
+			config.Compare{Mode: config.CompareModeMergeBase, Staged: true},
+			[]string{"src/api/committed.go", "src/api/staged.go"},
+		},
+		{
+			"include untracked",
+			config.Compare{Mode: config.CompareModeRange, Untracked: true},
+			[]string{"src/api/committed.go", "src/web/untracked.js"},
+		},
+	}
+
+	for _, provider := range providers {
+		for _, testCase := range testCases {
+			t.Run(provider+"/"+testCase.name, func(t *testing.T) {
+				testCase.compare.Provider = provider
+
+				a := newRepoAffected(repo, config.InputConfig{
+					Branch:  "main",
+					Compare: testCase.compare,
+				})
+
+				files, err := a.getGitFiles()
+
+				assert.NoError(t, err)
+				assert.Equal(t, testCase.expected, splitFiles(files))
+			})
+		}
+	}
+}
+
+// TestGetGitFilesRange checks that explicit refs can be used to compare a range of commits
+func TestGetGitFilesRange(t *testing.T) {
+	repo := newTestRepo(t)
+
+	repo.Write("src/api/main.go", "package main")
+	before := repo.Commit("api change")
+
+	repo.Write("src/web/index.js", "console.log()")
+	after := repo.Commit("web change")
+
+	repo.Write("src/infra/main.tf", "")
+	repo.Commit("infra change")
+
+	testCases := []struct {
+		name     string
+		compare  config.Compare
+		expected []string
+		err      bool
+	}{
+		{
+			"from and to",
+			config.Compare{From: before, To: after},
+			[]string{"src/web/index.js"},
+			false,
+		},
+		{
+			"since",
+			config.Compare{Since: after},
+			[]string{"src/infra/main.tf"},
+			false,
+		},
+		{
+			"from defaults to the branch",
+			config.Compare{To: "HEAD~1"},
+			[]string{"src/api/main.go", "src/web/index.js"},
+			false,
+		},
+		{
+			"unknown ref",
+			config.Compare{From: "does-not-exist"},
+			[]string{},
+			true,
+		},
+	}
+
+	root := repo.Git("rev-list", "--max-parents=0", "HEAD")
+
+	for _, provider := range providers {
+		for _, testCase := range testCases {
+			t.Run(provider+"/"+testCase.name, func(t *testing.T) {
+				testCase.compare.Provider = provider
+
+				a := newRepoAffected(repo, config.InputConfig{
+					Branch:  root,
+					Compare: testCase.compare,
+				})
+
+				files, err := a.getGitFiles()
+
+				assert.Equal(t, testCase.err, err != nil)
+				assert.Equal(t, testCase.expected, splitFiles(files))
+			})
+		}
+	}
+}
+
+// TestGetGitFilesRenamesAndDeletes checks that the status of each file is reported
+func TestGetGitFilesRenamesAndDeletes(t *testing.T) {
+	repo := newTestRepo(t)
+
+	repo.Write("src/old/main.go", "package main\n\nfunc main() {}\n")
+	repo.Write("src/gone/main.tf", "resource {}")
+	repo.Commit("add projects")
+
+	repo.Git("checkout", "-q", "-b", "feature")
+	repo.Git("mv", "src/old", "src/new")
+	repo.Git("rm", "-q", "-r", "src/gone")
+	repo.Write("src/api/main.go", "package main")
+	repo.Commit("move and remove")
+
+	expected := []models.ChangedFile{
+		{Status: models.ChangeAdded, Path: "src/api/main.go"},
+		{Status: models.ChangeDeleted, Path: "src/gone/main.tf"},
+		{Status: models.ChangeRenamed, Path: "src/new/main.go", OldPath: "src/old/main.go"},
+	}
+
+	for _, provider := range providers {
+		t.Run(provider, func(t *testing.T) {
+			a := newRepoAffected(repo, config.InputConfig{
+				Branch:  "main",
+				Compare: config.Compare{Provider: provider},
+			})
+
+			files, err := a.getGitFiles()
+
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, expected, files)
+		})
+	}
+}
+
+// TestGetGitFilesSubmodules checks that the files that have changed in an updated
+// submodule are included under the path of the submodule
+func TestGetGitFilesSubmodules(t *testing.T) {
+	lib := newTestRepo(t)
+	lib.Write("src/common.go", "package common")
+	lib.Commit("add library")
+
+	repo := newTestRepo(t)
+	repo.Git("submodule", "add", "-q", lib.Dir, "vendor/lib")
+	repo.Commit("add submodule")
+
+	// update the submodule on a feature branch
+	repo.Git("checkout", "-q", "-b", "feature")
+
+	submodule := &testRepo{t: t, Dir: filepath.Join(repo.Dir, "vendor", "lib")}
+	submodule.Write("src/common.go", "package common\n")
+	submodule.Write("src/extra.go", "package common")
+	submodule.Commit("update library")
+
+	repo.Commit("update submodule")
+
+	testCases := []struct {
+		name       string
+		submodules bool
+		expected   []string
+	}{
+		{"pointer only", false, []string{"vendor/lib"}},
+		{"recurse", true, []string{"vendor/lib", "vendor/lib/src/common.go", "vendor/lib/src/extra.go"}},
+	}
+
+	for _, provider := range providers {
+		for _, testCase := range testCases {
+			t.Run(provider+"/"+testCase.name, func(t *testing.T) {
+				a := newRepoAffected(repo, config.InputConfig{
+					Branch:  "main",
+					Compare: config.Compare{Provider: provider, Submodules: testCase.submodules},
+				})
+
+				files, err := a.getGitFiles()
+
+				assert.NoError(t, err)
+				assert.Equal(t, testCase.expected, splitFiles(files))
+			})
+		}
+	}
+}
+
+// TestGetGitFilesShallowClone checks that a shallow clone is deepened until the
+// merge-base can be found, or that the fallback policy is applied
+func TestGetGitFilesShallowClone(t *testing.T) {
+	origin := newTestRepo(t)
+	origin.Write("src/api/main.go", "package main")
+	origin.Commit("add api")
+
+	origin.Git("checkout", "-q", "-b", "feature")
+	origin.Write("src/web/index.js", "console.log()")
+	origin.Commit("feature change")
+
+	origin.Git("checkout", "-q", "main")
+	for _, content := range []string{"a", "b", "c"} {
+		origin.Write("src/api/main.go", content)
+		origin.Commit("main change")
+	}
+
+	testCases := []struct {
+		name     string
+		provider string
+		shallow  config.Shallow
+		expected []string
+		buildAll bool
+		err      bool
+	}{
+		{
+			"deepen until merge-base found",
+			config.GitProviderExec,
+			config.Shallow{Deepen: 1, Max: 10, Fallback: config.ShallowFallbackFail},
+			[]string{"src/web/index.js"},
+			false,
+			false,
+		},
+		{
+			"fail without deepening",
+			config.GitProviderExec,
+			config.Shallow{Fallback: config.ShallowFallbackFail},
+			[]string{},
+			false,
+			true,
+		},
+		{
+			"build all when max reached",
+			config.GitProviderExec,
+			config.Shallow{Deepen: 1, Max: 1, Fallback: config.ShallowFallbackAll},
+			[]string{},
+			true,
+			false,
+		},
+		{
+			"native cannot deepen",
+			config.GitProviderNative,
+			config.Shallow{Deepen: 1, Max: 10, Fallback: config.ShallowFallbackAll},
+			[]string{},
+			true,
+			false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+
+			// create a new shallow clone for each test as deepening changes the clone
+			clone := &testRepo{t: t, Dir: t.TempDir()}
+			clone.Git("clone", "-q", "--depth", "1", "--no-single-branch", "file://"+filepath.ToSlash(origin.Dir), ".")
+			clone.Git("checkout", "-q", "feature")
+
+			a := newRepoAffected(clone, config.InputConfig{
+				Branch:  "origin/main",
+				Compare: config.Compare{Provider: testCase.provider},
+				Shallow: testCase.shallow,
+			})
+
+			files, err := a.getGitFiles()
+
+			assert.Equal(t, testCase.err, err != nil)
+			assert.Equal(t, testCase.buildAll, a.buildAll != "")
+			assert.Equal(t, testCase.expected, splitFiles(files))
+		})
+	}
+}
+
+// TestGetProviderNotRepository checks that the native provider reports an error each time
+// it is requested outside of a repository, rather than returning a nil provider
+func TestGetProviderNotRepository(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	cfg := &config.Config{}
+	cfg.Input.Directory.WorkingDir = t.TempDir()
+	cfg.Input.Compare.Provider = config.GitProviderNative
+
+	affected := New(&models.App{Logger: logger}, cfg, logger)
+
+	for i := 0; i < 2; i++ {
+		provider, err := affected.getProvider()
+		assert.Error(t, err)
+		assert.Nil(t, provider)
+	}
+
+	_, _, err := affected.getComparison()
+	assert.Error(t, err)
+}
+
+// TestGetGitFilesRemoteBranch checks that a detected branch that only exists on the
+// origin remote, as in the checkout of a pull request by a CI/CD system, is compared against
+func TestGetGitFilesRemoteBranch(t *testing.T) {
+	origin := newTestRepo(t)
+	origin.Write("src/web/index.js", "console.log()")
+	origin.Commit("main change")
+
+	// clone the repository and remove the local copy of the base branch
+	clone := &testRepo{t: t, Dir: t.TempDir()}
+	clone.Git("clone", "-q", "file://"+filepath.ToSlash(origin.Dir), ".")
+	clone.Git("checkout", "-q", "-b", "feature")
+	clone.Git("branch", "-q", "-D", "main")
+	clone.Write("src/api/main.go", "package main")
+	clone.Commit("feature change")
+
+	for _, provider := range providers {
+		for _, mode := range []string{config.CompareModeMergeBase, config.CompareModeTip, config.CompareModeRange} {
+			t.Run(provider+"/"+mode, func(t *testing.T) {
+				a := newRepoAffected(clone, config.InputConfig{
+					Branch:  "main",
+					Compare: config.Compare{Mode: mode, Provider: provider},
+				})
+				a.Config.BranchDetected = true
+
+				files, err := a.getGitFiles()
+
+				assert.NoError(t, err)
+				assert.Equal(t, []string{"src/api/main.go"}, splitFiles(files))
+			})
+		}
+	}
+
+	// a branch that has been set explicitly is not changed
+	a := newRepoAffected(clone, config.InputConfig{Branch: "main"})
+
+	_, err := a.getGitFiles()
+	assert.Error(t, err)
+}
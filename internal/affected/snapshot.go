@@ -0,0 +1,43 @@
+package affected
+
+import (
+	"errors"
+	"os"
+
+	"github.com/amido/mrbuild/internal/models"
+	"github.com/amido/mrbuild/internal/snapshot"
+	log "github.com/sirupsen/logrus"
+)
+
+// getSnapshotFiles determines the files that have changed by hashing the files in
+// each project and comparing them with the manifest saved by the last successful run
+// If there is no manifest then all of the files are treated as having been added
+func (a *Affected) getSnapshotFiles() ([]models.ChangedFile, error) {
+
+	path := snapshot.GetManifestPath(a.Config)
+
+	current, err := snapshot.Take(a.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	previous, err := snapshot.Load(path)
+	if errors.Is(err, os.ErrNotExist) {
+		a.Logger.Warnf("Snapshot manifest cannot be found, all files will be treated as added: %s", path)
+		previous = snapshot.NewManifest()
+	} else if err != nil {
+		return nil, err
+	}
+
+	files := current.Compare(previous)
+
+	a.Logger.WithFields(
+		log.Fields{
+			"manifest": path,
+			"files":    len(current.Files),
+			"changed":  len(files),
+		},
+	).Info("Comparing changes against snapshot")
+
+	return files, nil
+}
@@ -0,0 +1,104 @@
+package affected
+
+import (
+	"regexp"
+
+	"github.com/amido/mrbuild/internal/config"
+	"github.com/amido/mrbuild/internal/models"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// trivialWhitespace states that the only changes to the file are to whitespace
+	trivialWhitespace = "whitespace"
+
+	// trivialComments states that the only changes to the file are to comment lines
+	trivialComments = "comments"
+)
+
+// isTrivial determines if all of the files that matched the project only have changes, since
+// the from commit, to whitespace or to lines that match the comment patterns of the project
+// The diff of each file is read from git, so if the changes did not come from git, or
+// the diff cannot be read, the changes are not treated as trivial
+func (a *Affected) isTrivial(project config.Project, matched []models.ChangedFile, from string) bool {
+
+	if from == "" {
+		a.Logger.Warnf("Unable to check for trivial changes as the changes were not read from git: %s", project.Name)
+		return false
+	}
+
+	provider, err := a.getProvider()
+	if err != nil {
+		return false
+	}
+
+	// determine the reason that each file is trivial before logging any of them, so that
+	// files are only reported as discounted if the whole project is skipped
+	reasons := make([]string, len(matched))
+
+	for i, file := range matched {
+
+		// only modified files can have trivial changes, adding, removing or moving
+		// a file is always significant
+		if file.Status != models.ChangeModified {
+			return false
+		}
+
+		lines, err := provider.ChangedLines(from, a.to, a.Config.Input.Compare.Staged, file.Path)
+		if err != nil {
+			a.Logger.Debugf("Unable to read the changes to %s: %s", file.Path, err.Error())
+			return false
+		}
+
+		reasons[i] = getTrivialReason(lines, project.CommentPatterns)
+		if reasons[i] == "" {
+			return false
+		}
+	}
+
+	for i, file := range matched {
+		a.Logger.WithFields(
+			log.Fields{
+				"project": project.Name,
+				"file":    file.Path,
+				"reason":  reasons[i],
+			},
+		).Info("Discounting trivial change")
+	}
+
+	return len(matched) > 0
+}
+
+// getTrivialReason returns the reason that the changed lines are trivial, or an empty
+// string if any of the lines are a significant change
+func getTrivialReason(lines []string, patterns []string) string {
+
+	if len(lines) == 0 {
+		return trivialWhitespace
+	}
+
+	var comments []*regexp.Regexp
+	for _, pattern := range patterns {
+		comments = append(comments, regexp.MustCompile(pattern))
+	}
+
+	for _, line := range lines {
+		if !matchesAny(line, comments) {
+			return ""
+		}
+	}
+
+	return trivialComments
+}
+
+// matchesAny determines if the line matches any of the regular expressions
+func matchesAny(line string, comments []*regexp.Regexp) bool {
+
+	for _, re := range comments {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,124 @@
+package affected
+
+import (
+	"testing"
+
+	"github.com/amido/mrbuild/internal/config"
+	"github.com/amido/mrbuild/internal/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetTrivialReason(t *testing.T) {
+
+	comments := []string{`^\s*#`, `^\s*//`}
+
+	tables := []struct {
+		name     string
+		lines    []string
+		patterns []string
+		expected string
+	}{
+		{"no changed lines", nil, comments, trivialWhitespace},
+		{"only comments", []string{"# old comment", "  # new comment", "// note"}, comments, trivialComments},
+		{"code and comments", []string{"# comment", "count = 2"}, comments, ""},
+		{"comments without patterns", []string{"# comment"}, nil, ""},
+	}
+
+	for _, table := range tables {
+		assert.Equal(t, table.expected, getTrivialReason(table.lines, table.patterns), table.name)
+	}
+}
+
+// TestGetProjectsIgnoreTrivial checks that projects with only whitespace or comment
+// changes are skipped when ignore_trivial has been set
+func TestGetProjectsIgnoreTrivial(t *testing.T) {
+
+	tables := []struct {
+		name     string
+		tf       string
+		patterns []string
+		trivial  bool
+		expected []string
+	}{
+		{
+			name:     "whitespace only",
+			tf:       "resource \"a\"   {\n\n  count = 1\n}\n# comment\n",
+			trivial:  true,
+			expected: []string{"api"},
+		},
+		{
+			name:     "comment only",
+			tf:       "resource \"a\" {\n    count = 1\n}\n# changed comment\n",
+			patterns: []string{`^\s*#`},
+			trivial:  true,
+			expected: []string{"api"},
+		},
+		{
+			name:     "comment without patterns",
+			tf:       "resource \"a\" {\n    count = 1\n}\n# changed comment\n",
+			trivial:  true,
+			expected: []string{"api", "infra"},
+		},
+		{
+			name:     "significant change",
+			tf:       "resource \"a\" {\n    count = 2\n}\n# comment\n",
+			patterns: []string{`^\s*#`},
+			trivial:  true,
+			expected: []string{"api", "infra"},
+		},
+		{
+			name:     "not enabled",
+			tf:       "resource \"a\"   {\n\n  count = 1\n}\n# comment\n",
+			expected: []string{"api", "infra"},
+		},
+	}
+
+	for _, provider := range providers {
+		for _, table := range tables {
+			repo := newTestRepo(t)
+			repo.Write("src/infra/main.tf", "resource \"a\" {\n    count = 1\n}\n# comment\n")
+			repo.Write("src/api/main.go", "package main\n")
+			repo.Commit("add projects")
+
+			repo.Git("checkout", "-q", "-b", "feature")
+			repo.Write("src/infra/main.tf", table.tf)
+			repo.Write("src/api/main.go", "package main\n\nfunc main() {}\n")
+			repo.Commit("feature change")
+
+			affected := newRepoAffected(repo, config.InputConfig{
+				Branch:  "main",
+				Compare: config.Compare{Provider: provider},
+				Projects: []config.Project{
+					{Name: "api", Folder: "src/api", Patterns: []string{".*\\.go"}, IgnoreTrivial: table.trivial},
+					{Name: "infra", Folder: "src/infra", Patterns: []string{".*\\.tf"}, IgnoreTrivial: table.trivial, CommentPatterns: table.patterns},
+				},
+			})
+
+			files, err := affected.getGitFiles()
+			assert.NoError(t, err)
+
+			actual := []string{}
+			for _, spawn := range affected.getProjects(files) {
+				actual = append(actual, spawn.Name)
+			}
+
+			assert.Equal(t, table.expected, actual, "%s: %s", provider, table.name)
+		}
+	}
+}
+
+// TestGetProjectsIgnoreTrivialWithoutGit checks that the changes are not treated as trivial
+// when they have not been read from git, as the diff is not available
+func TestGetProjectsIgnoreTrivialWithoutGit(t *testing.T) {
+	repo := newTestRepo(t)
+
+	affected := newRepoAffected(repo, config.InputConfig{
+		Projects: []config.Project{
+			{Name: "infra", Folder: "src/infra", Patterns: []string{".*\\.tf"}, IgnoreTrivial: true},
+		},
+	})
+
+	spawns := affected.getProjects(parser.ParseLines("src/infra/main.tf"))
+
+	assert.Len(t, spawns, 1, "The project should be built")
+}
@@ -0,0 +1,128 @@
+package ci
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	// GitHub is the name of GitHub Actions
+	GitHub = "github"
+
+	// AzureDevOps is the name of Azure DevOps Pipelines
+	AzureDevOps = "azure-devops"
+
+	// GitLab is the name of GitLab CI
+	GitLab = "gitlab"
+
+	// Jenkins is the name of Jenkins
+	Jenkins = "jenkins"
+
+	// Bitbucket is the name of Bitbucket Pipelines
+	Bitbucket = "bitbucket"
+)
+
+// Context holds the details of the build that have been detected from the CI/CD system
+// All of the values are empty if the build is not running in a known CI/CD system
+type Context struct {
+	System      string // Name of the CI/CD system
+	BaseBranch  string // Branch that the pull request will be merged into
+	HeadSha     string // SHA of the commit that is being built
+	PullRequest string // Number of the pull request, if the build is for one
+	BuildID     string // Identifier of the build in the CI/CD system
+	EventPath   string // Path to the payload of the event that triggered the build, GitHub Actions only
+}
+
+// system holds the names of the environment variables that a CI/CD system sets
+type system struct {
+	name        string
+	marker      string // Variable that is always set by the system
+	baseBranch  string
+	headSha     string
+	pullRequest string
+	buildID     string
+	eventPath   string
+}
+
+// systems is the list of CI/CD systems that can be detected, in the order they are checked
+var systems = []system{
+	{
+		name:        GitHub,
+		marker:      "GITHUB_ACTIONS",
+		baseBranch:  "GITHUB_BASE_REF",
+		headSha:     "GITHUB_SHA",
+		pullRequest: "GITHUB_REF",
+		buildID:     "GITHUB_RUN_ID",
+		eventPath:   "GITHUB_EVENT_PATH",
+	},
+	{
+		name:        AzureDevOps,
+		marker:      "TF_BUILD",
+		baseBranch:  "SYSTEM_PULLREQUEST_TARGETBRANCH",
+		headSha:     "BUILD_SOURCEVERSION",
+		pullRequest: "SYSTEM_PULLREQUEST_PULLREQUESTID",
+		buildID:     "BUILD_BUILDID",
+	},
+	{
+		name:        GitLab,
+		marker:      "GITLAB_CI",
+		baseBranch:  "CI_MERGE_REQUEST_TARGET_BRANCH_NAME",
+		headSha:     "CI_COMMIT_SHA",
+		pullRequest: "CI_MERGE_REQUEST_IID",
+		buildID:     "CI_PIPELINE_ID",
+	},
+	{
+		name:        Jenkins,
+		marker:      "JENKINS_URL",
+		baseBranch:  "CHANGE_TARGET",
+		headSha:     "GIT_COMMIT",
+		pullRequest: "CHANGE_ID",
+		buildID:     "BUILD_ID",
+	},
+	{
+		name:        Bitbucket,
+		marker:      "BITBUCKET_BUILD_NUMBER",
+		baseBranch:  "BITBUCKET_PR_DESTINATION_BRANCH",
+		headSha:     "BITBUCKET_COMMIT",
+		pullRequest: "BITBUCKET_PR_ID",
+		buildID:     "BITBUCKET_BUILD_NUMBER",
+	},
+}
+
+// githubPullRequest matches the ref that GitHub Actions sets for a pull request
+var githubPullRequest = regexp.MustCompile(`^refs/pull/(\d+)/`)
+
+// Detect determines the CI/CD system that is running the build, from the environment
+// variables that are read using getenv, and returns the details of the build
+func Detect(getenv func(string) string) Context {
+
+	for _, s := range systems {
+		if getenv(s.marker) == "" {
+			continue
+		}
+
+		context := Context{
+			System:      s.name,
+			BaseBranch:  strings.TrimPrefix(getenv(s.baseBranch), "refs/heads/"),
+			HeadSha:     getenv(s.headSha),
+			PullRequest: getenv(s.pullRequest),
+			BuildID:     getenv(s.buildID),
+		}
+
+		if s.eventPath != "" {
+			context.EventPath = getenv(s.eventPath)
+		}
+
+		// GitHub Actions does not set the number of the pull request, so it is read from the ref
+		if s.name == GitHub {
+			context.PullRequest = ""
+			if match := githubPullRequest.FindStringSubmatch(getenv(s.pullRequest)); match != nil {
+				context.PullRequest = match[1]
+			}
+		}
+
+		return context
+	}
+
+	return Context{}
+}
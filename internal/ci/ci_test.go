@@ -0,0 +1,95 @@
+package ci
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetect(t *testing.T) {
+
+	tables := []struct {
+		name     string
+		env      map[string]string
+		expected Context
+	}{
+		{
+			name:     "not in ci",
+			env:      map[string]string{"HOME": "/home/mrbuild"},
+			expected: Context{},
+		},
+		{
+			name: "github pull request",
+			env: map[string]string{
+				"GITHUB_ACTIONS":    "true",
+				"GITHUB_BASE_REF":   "main",
+				"GITHUB_SHA":        "abc123",
+				"GITHUB_REF":        "refs/pull/42/merge",
+				"GITHUB_RUN_ID":     "1001",
+				"GITHUB_EVENT_PATH": "/tmp/event.json",
+			},
+			expected: Context{System: GitHub, BaseBranch: "main", HeadSha: "abc123", PullRequest: "42", BuildID: "1001", EventPath: "/tmp/event.json"},
+		},
+		{
+			name: "github push",
+			env: map[string]string{
+				"GITHUB_ACTIONS": "true",
+				"GITHUB_SHA":     "abc123",
+				"GITHUB_REF":     "refs/heads/main",
+				"GITHUB_RUN_ID":  "1002",
+			},
+			expected: Context{System: GitHub, HeadSha: "abc123", BuildID: "1002"},
+		},
+		{
+			name: "azure devops pull request",
+			env: map[string]string{
+				"TF_BUILD":                         "True",
+				"SYSTEM_PULLREQUEST_TARGETBRANCH":  "refs/heads/develop",
+				"BUILD_SOURCEVERSION":              "def456",
+				"SYSTEM_PULLREQUEST_PULLREQUESTID": "17",
+				"BUILD_BUILDID":                    "2001",
+			},
+			expected: Context{System: AzureDevOps, BaseBranch: "develop", HeadSha: "def456", PullRequest: "17", BuildID: "2001"},
+		},
+		{
+			name: "gitlab merge request",
+			env: map[string]string{
+				"GITLAB_CI":                           "true",
+				"CI_MERGE_REQUEST_TARGET_BRANCH_NAME": "main",
+				"CI_COMMIT_SHA":                       "789abc",
+				"CI_MERGE_REQUEST_IID":                "5",
+				"CI_PIPELINE_ID":                      "3001",
+			},
+			expected: Context{System: GitLab, BaseBranch: "main", HeadSha: "789abc", PullRequest: "5", BuildID: "3001"},
+		},
+		{
+			name: "jenkins change request",
+			env: map[string]string{
+				"JENKINS_URL":   "https://jenkins.example.com/",
+				"CHANGE_TARGET": "main",
+				"GIT_COMMIT":    "fed987",
+				"CHANGE_ID":     "8",
+				"BUILD_ID":      "4001",
+			},
+			expected: Context{System: Jenkins, BaseBranch: "main", HeadSha: "fed987", PullRequest: "8", BuildID: "4001"},
+		},
+		{
+			name: "bitbucket pull request",
+			env: map[string]string{
+				"BITBUCKET_BUILD_NUMBER":          "5001",
+				"BITBUCKET_PR_DESTINATION_BRANCH": "master",
+				"BITBUCKET_COMMIT":                "cba321",
+				"BITBUCKET_PR_ID":                 "9",
+			},
+			expected: Context{System: Bitbucket, BaseBranch: "master", HeadSha: "cba321", PullRequest: "9", BuildID: "5001"},
+		},
+	}
+
+	for _, table := range tables {
+		getenv := func(name string) string {
+			return table.env[name]
+		}
+
+		assert.Equal(t, table.expected, Detect(getenv), table.name)
+	}
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// MarkerNone does not record or use markers for the last successful build
+	MarkerNone = "none"
+
+	// MarkerTag records the last successful build of each project as a git tag
+	MarkerTag = "tag"
+
+	// MarkerNotes records the last successful build of each project in git notes
+	MarkerNotes = "notes"
+
+	// DefaultMarkerPrefix is the prefix of the tags, and the name of the notes ref, for the markers
+	DefaultMarkerPrefix = "mrbuild"
+)
+
+// Baseline holds the settings for comparing each project against the commit of
+// its last successful build, rather than the branch
+type Baseline struct {
+	Marker string `mapstructure:"marker"` // How the last successful build is recorded, none, tag or notes
+	Prefix string `mapstructure:"prefix"` // Prefix of the tags or the name of the notes ref
+}
+
+// Check ensures that the type of marker is valid and sets the default prefix
+func (b *Baseline) Check() error {
+	b.Marker = strings.ToLower(strings.TrimSpace(b.Marker))
+
+	switch b.Marker {
+	case "":
+		b.Marker = MarkerNone
+	case MarkerNone, MarkerTag, MarkerNotes:
+	default:
+		return fmt.Errorf("unknown marker '%s', must be one of %s, %s or %s", b.Marker, MarkerNone, MarkerTag, MarkerNotes)
+	}
+
+	b.Prefix = strings.Trim(strings.TrimSpace(b.Prefix), "/")
+	if b.Prefix == "" {
+		b.Prefix = DefaultMarkerPrefix
+	}
+
+	return nil
+}
+
+// Enabled states if markers should be used for the last successful build
+func (b *Baseline) Enabled() bool {
+	return b.Marker == MarkerTag || b.Marker == MarkerNotes
+}
+
+// GetTagName returns the name of the tag that marks the last successful build of the project
+func (b *Baseline) GetTagName(project string) string {
+	return fmt.Sprintf("%s/%s/last-success", b.Prefix, project)
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// CompareModeMergeBase compares the changes against the merge-base of HEAD and the branch
+	CompareModeMergeBase = "merge-base"
+
+	// CompareModeTip compares the changes against the tip of the branch
+	CompareModeTip = "tip"
+
+	// CompareModeRange compares two commits, ignoring any changes in the working tree
+	// By default this is the branch and HEAD
+	CompareModeRange = "range"
+
+	// GitProviderExec runs the git executable to read the repository
+	GitProviderExec = "exec"
+
+	// GitProviderNative reads the repository in-process without the git executable
+	GitProviderNative = "native"
+)
+
+// Compare holds the settings that determine how the list of changed files is generated
+type Compare struct {
+	Mode       string `mapstructure:"mode"`       // How the changes should be compared against the branch
+	Worktree   bool   `mapstructure:"worktree"`   // Include staged and unstaged changes in the working tree, in range mode
+	Staged     bool   `mapstructure:"staged"`     // Only include the staged changes from the working tree
+	Untracked  bool   `mapstructure:"untracked"`  // Include files that are not tracked by git
+	From       string `mapstructure:"from"`       // Ref at the start of the range to compare
+	To         string `mapstructure:"to"`         // Ref at the end of the range to compare
+	Since      string `mapstructure:"since"`      // Ref to compare with HEAD
+	Provider   string `mapstructure:"provider"`   // How the git repository should be read, exec or native
+	Submodules bool   `mapstructure:"submodules"` // Include the files that have changed in updated submodules
+}
+
+// Check ensures that the compare mode has been set to a valid value and that the
+// options do not conflict. If no mode has been set, the merge-base mode is used, unless
+// refs for a range have been specified
+func (c *Compare) Check() error {
+	c.Mode = strings.ToLower(strings.TrimSpace(c.Mode))
+
+	// setting any of the refs for a range means that the range mode must be used
+	if c.Since != "" && c.From != "" {
+		return fmt.Errorf("since and from cannot be used together")
+	}
+
+	if c.From != "" || c.To != "" || c.Since != "" {
+		c.Mode = CompareModeRange
+	}
+
+	switch c.Mode {
+	case "":
+		c.Mode = CompareModeMergeBase
+	case CompareModeMergeBase, CompareModeTip, CompareModeRange:
+	default:
+		return fmt.Errorf("unknown base mode '%s', must be one of %s, %s or %s", c.Mode, CompareModeMergeBase, CompareModeTip, CompareModeRange)
+	}
+
+	c.Provider = strings.ToLower(strings.TrimSpace(c.Provider))
+
+	switch c.Provider {
+	case "":
+		c.Provider = GitProviderExec
+	case GitProviderExec, GitProviderNative:
+	default:
+		return fmt.Errorf("unknown git provider '%s', must be one of %s or %s", c.Provider, GitProviderExec, GitProviderNative)
+	}
+
+	// staged only excludes the unstaged changes, so it cannot be used with the whole working tree
+	if c.Worktree && c.Staged {
+		return fmt.Errorf("include-worktree and staged-only cannot be used together")
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareCheck(t *testing.T) {
+
+	// create a list of tests to carry out
+	testCases := []struct {
+		mode     string
+		expected string
+		err      bool
+	}{
+		{"", CompareModeMergeBase, false},
+		{"tip", CompareModeTip, false},
+		{" Range ", CompareModeRange, false},
+		{"merge-base", CompareModeMergeBase, false},
+		{"sideways", "sideways", true},
+	}
+
+	for _, testCase := range testCases {
+		compare := Compare{Mode: testCase.mode}
+
+		err := compare.Check()
+
+		assert.Equal(t, testCase.err, err != nil, "Error state for mode '%s' is not as expected", testCase.mode)
+		assert.Equal(t, testCase.expected, compare.Mode)
+	}
+}
+
+func TestCompareCheckWorktreeOptions(t *testing.T) {
+
+	compare := Compare{Worktree: true, Staged: true}
+	assert.Error(t, compare.Check(), "Worktree and staged only should not be allowed together")
+
+	compare = Compare{Staged: true, Untracked: true}
+	assert.NoError(t, compare.Check())
+}
+
+func TestCompareCheckRange(t *testing.T) {
+
+	// create a list of tests to carry out
+	testCases := []struct {
+		compare  Compare
+		expected string
+		err      bool
+	}{
+		{Compare{Mode: "merge-base", From: "abc123", To: "def456"}, CompareModeRange, false},
+		{Compare{Mode: "tip", Since: "v1.0.0"}, CompareModeRange, false},
+		{Compare{To: "HEAD~1"}, CompareModeRange, false},
+		{Compare{From: "abc123", Since: "v1.0.0"}, "", true},
+	}
+
+	for _, testCase := range testCases {
+		err := testCase.compare.Check()
+
+		assert.Equal(t, testCase.err, err != nil)
+		assert.Equal(t, testCase.expected, testCase.compare.Mode)
+	}
+}
+
+func TestShallowCheck(t *testing.T) {
+
+	shallow := Shallow{}
+	assert.NoError(t, shallow.Check())
+	assert.Equal(t, ShallowFallbackFail, shallow.Fallback, "Fallback should default to fail")
+	assert.Equal(t, 0, shallow.Max)
+
+	shallow = Shallow{Deepen: 50, Fallback: "ALL"}
+	assert.NoError(t, shallow.Check())
+	assert.Equal(t, ShallowFallbackAll, shallow.Fallback)
+	assert.Equal(t, 500, shallow.Max, "Max should default to 10 steps")
+
+	shallow = Shallow{Fallback: "ignore"}
+	assert.Error(t, shallow.Check())
+}
+
+func TestSnapshotCheck(t *testing.T) {
+
+	snapshot := Snapshot{}
+	assert.NoError(t, snapshot.Check())
+	assert.Equal(t, DefaultSnapshotManifest, snapshot.Manifest, "Manifest should default to the file in the working directory")
+
+	snapshot = Snapshot{Manifest: " /cache/snapshot.json "}
+	assert.NoError(t, snapshot.Check())
+	assert.Equal(t, "/cache/snapshot.json", snapshot.Manifest)
+}
+
+func TestBaselineCheck(t *testing.T) {
+
+	baseline := Baseline{}
+	assert.NoError(t, baseline.Check())
+	assert.Equal(t, MarkerNone, baseline.Marker, "Markers should be off by default")
+	assert.False(t, baseline.Enabled())
+
+	baseline = Baseline{Marker: "Tag", Prefix: "/ci/"}
+	assert.NoError(t, baseline.Check())
+	assert.True(t, baseline.Enabled())
+	assert.Equal(t, "ci/api/last-success", baseline.GetTagName("api"))
+
+	baseline = Baseline{Marker: "branch"}
+	assert.Error(t, baseline.Check())
+}
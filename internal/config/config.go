@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/amido/mrbuild/internal/ci"
 	"github.com/amido/mrbuild/internal/constants"
 	"github.com/amido/mrbuild/internal/util"
 	"github.com/sirupsen/logrus"
@@ -18,6 +19,13 @@ type Config struct {
 	Input InputConfig
 	Self  SelfConfig
 
+	// details of the build from the CI/CD system that is running it, if any
+	CI ci.Context
+
+	// states that the branch has not been set explicitly, so it has been detected from the
+	// CI/CD system or the default is being used
+	BranchDetected bool
+
 	// allow environment variables to be set
 	envvars map[string]string
 }
@@ -27,11 +35,82 @@ type Config struct {
 func (c *Config) Check() error {
 	var err error
 
-	// iterate around the projects and check that branch has a default value
+	// detect the CI/CD system so that the details of the build can be used as defaults
+	c.CI = ci.Detect(os.Getenv)
+
+	// ensure that the branch has a default value, the branch that a pull request
+	// will be merged into is used if it has not been set explicitly
+	if strings.TrimSpace(c.Input.Branch) == "" {
+		c.Input.Branch = c.CI.BaseBranch
+		c.BranchDetected = true
+	}
+
 	if strings.TrimSpace(c.Input.Branch) == "" {
 		c.Input.Branch = "main"
 	}
 
+	// apply the defaults and templates to each of the projects
+	err = c.Input.ResolveProjects()
+	if err != nil {
+		return err
+	}
+
+	// check the settings of each of the projects
+	for i := range c.Input.Projects {
+		err = c.Input.Projects[i].Check()
+		if err != nil {
+			return err
+		}
+	}
+
+	// ensure that the rules to discover projects are valid
+	err = c.Input.Discovery.Check()
+	if err != nil {
+		return err
+	}
+
+	// ensure that the patterns of the files that affect every project are valid
+	err = c.Input.CheckGlobalTriggers()
+	if err != nil {
+		return err
+	}
+
+	// ensure that the way in which the changes are compared is valid
+	err = c.Input.Compare.Check()
+	if err != nil {
+		return err
+	}
+
+	// ensure that the policy for shallow clones is valid
+	err = c.Input.Shallow.Check()
+	if err != nil {
+		return err
+	}
+
+	// ensure that the marker for the last successful build is valid
+	err = c.Input.Baseline.Check()
+	if err != nil {
+		return err
+	}
+
+	// ensure that the snapshot manifest has been set
+	err = c.Input.Snapshot.Check()
+	if err != nil {
+		return err
+	}
+
+	// ensure that the options to select the projects are valid
+	err = c.Input.Options.Check()
+	if err != nil {
+		return err
+	}
+
+	// ensure that the format of the input data is known
+	err = c.Input.CheckInputFormat()
+	if err != nil {
+		return err
+	}
+
 	// set necessary default values
 	c.SetDefaultValues()
 
@@ -69,21 +148,40 @@ func (config *Config) GetVersion() string {
 
 // ExecuteCommand executes the command and arguments that have been supplied to the function
 func (config *Config) ExecuteCommand(path string, logger *logrus.Logger, command string, arguments string, show bool, force bool) (string, error) {
+	return config.ExecuteCommandEnv(path, logger, command, arguments, nil, show, force)
+}
+
+// ExecuteCommandEnv executes the command with the additional environment variables, which
+// are set after, and so take precedence over, those in the configuration
+func (config *Config) ExecuteCommandEnv(path string, logger *logrus.Logger, command string, arguments string, env map[string]string, show bool, force bool) (string, error) {
+
+	// get the command and arguments
+	cmd, args := util.BuildCommand(command, arguments)
+
+	return config.execute(path, logger, cmd, args, fmt.Sprintf("%s %s", command, arguments), env, show, force)
+}
+
+// ExecuteArgs executes the command with each argument passed to it as supplied, without
+// splitting the arguments on whitespace or running the command through a shell
+func (config *Config) ExecuteArgs(path string, logger *logrus.Logger, command string, arguments []string, show bool, force bool) (string, error) {
+	return config.execute(path, logger, command, arguments, strings.Join(append([]string{command}, arguments...), " "), nil, show, force)
+}
+
+// execute runs the command and arguments, the commandLine is how the command is written
+// to the debug and command logs
+func (config *Config) execute(path string, logger *logrus.Logger, cmd string, args []string, commandLine string, env map[string]string, show bool, force bool) (string, error) {
 
 	var result bytes.Buffer
 	var err error
 	var mwriter io.Writer
 	var writers []io.Writer
 
-	// get the command and arguments
-	cmd, args := util.BuildCommand(command, arguments)
-
 	// output the command being run if in debug mode
-	logger.Debugf("Command: %s %s", command, arguments)
+	logger.Debugf("Command: %s", commandLine)
 
 	// Write out the command log
 	if path != "" {
-		err = config.WriteCmdLog(path, fmt.Sprintf("%s %s", command, arguments))
+		err = config.WriteCmdLog(path, commandLine)
 		if err != nil {
 			logger.Warnf("Unable to write command to log: %s", err.Error())
 		}
@@ -116,7 +214,7 @@ func (config *Config) ExecuteCommand(path string, logger *logrus.Logger, command
 	cmdLine.Stderr = mwriter
 
 	// determine if any environment variables need to be set
-	if len(config.envvars) > 0 {
+	if len(config.envvars) > 0 || len(env) > 0 {
 
 		// ensure that the current envvars are preserved
 		cmdLine.Env = os.Environ()
@@ -125,11 +223,17 @@ func (config *Config) ExecuteCommand(path string, logger *logrus.Logger, command
 		for name, value := range config.envvars {
 			cmdLine.Env = append(cmdLine.Env, fmt.Sprintf("%s=%s", name, value))
 		}
+
+		for name, value := range env {
+			cmdLine.Env = append(cmdLine.Env, fmt.Sprintf("%s=%s", name, value))
+		}
 	}
 
 	// set the path for the command, if it exists
 	if util.Exists(path) {
 		cmdLine.Dir = path
+	} else if path != "" {
+		logger.Warnf("Directory does not exist, running command in current directory: %s", path)
 	}
 
 	// only run the command if not in dryrun mode
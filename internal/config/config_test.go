@@ -28,3 +28,181 @@ func TestGetVersion(t *testing.T) {
 
 	assert.Equal(t, actual, config.Input.Version)
 }
+
+func TestCheckInputFormat(t *testing.T) {
+	input := InputConfig{}
+
+	assert.NoError(t, input.CheckInputFormat())
+	assert.Equal(t, InputFormatLines, input.InputFormat, "Input format should default to lines")
+
+	input.InputFormat = " GitHub "
+	assert.NoError(t, input.CheckInputFormat())
+	assert.Equal(t, InputFormatGitHub, input.InputFormat)
+
+	input.InputFormat = "yaml"
+	assert.Error(t, input.CheckInputFormat())
+}
+
+func TestCheckProjects(t *testing.T) {
+	config := Config{}
+	config.Input.Projects = []Project{
+		{Name: "infra", CommentPatterns: []string{`^\s*#`}},
+	}
+
+	assert.NoError(t, config.Check())
+
+	config.Input.Projects[0].CommentPatterns = append(config.Input.Projects[0].CommentPatterns, `^\s*(#`)
+	assert.Error(t, config.Check(), "An invalid comment pattern should be reported")
+}
+
+func TestCheckBranchFromCI(t *testing.T) {
+	t.Setenv("GITHUB_ACTIONS", "true")
+	t.Setenv("GITHUB_BASE_REF", "develop")
+
+	config := Config{}
+	assert.NoError(t, config.Check())
+	assert.Equal(t, "develop", config.Input.Branch, "The base branch of the pull request should be used")
+	assert.Equal(t, "github", config.CI.System)
+	assert.True(t, config.BranchDetected)
+
+	config = Config{}
+	config.Input.Branch = "release"
+	assert.NoError(t, config.Check())
+	assert.Equal(t, "release", config.Input.Branch, "An explicit branch should not be overridden")
+	assert.False(t, config.BranchDetected)
+}
+
+func TestCheckProjectGlobs(t *testing.T) {
+	config := Config{}
+	config.Input.Projects = []Project{
+		{Name: "infra", Folder: "src/infra", Globs: []string{"**/*.tf"}},
+	}
+
+	assert.NoError(t, config.Check())
+	assert.True(t, config.Input.Projects[0].Match("src/infra/main.tf"))
+	assert.False(t, config.Input.Projects[0].Match("src/api/main.tf"))
+
+	config.Input.Projects[0].Globs = []string{"**/[a-z.tf"}
+	assert.Error(t, config.Check(), "An invalid glob should be reported")
+}
+
+func TestProjectMatchAnchored(t *testing.T) {
+
+	tables := []struct {
+		name     string
+		project  Project
+		file     string
+		expected bool
+	}{
+		{"file in folder", Project{Folder: "src/api", Patterns: []string{".*"}}, "src/api/main.go", true},
+		{"folder nested in another folder", Project{Folder: "src/api", Patterns: []string{".*"}}, "legacy/src/api/main.go", false},
+		{"folder with the same prefix", Project{Folder: "src/a", Patterns: []string{".*"}}, "src/ab/main.go", false},
+		{"pattern matches part of the path", Project{Folder: "src/api", Patterns: []string{"docs/.*"}}, "src/api/v1/docs/index.md", false},
+		{"folder with special characters", Project{Folder: "src/api.v1", Patterns: []string{".*"}}, "src/apixv1/main.go", false},
+		{"root folder", Project{Folder: ".", Patterns: []string{"go\\.mod"}}, "go.mod", true},
+		{"alternation in folder", Project{Folder: "src/api", Patterns: []string{"foo|bar"}}, "src/api/bar.go", true},
+		{"alternation outside folder", Project{Folder: "src/api", Patterns: []string{"foo|bar"}}, "legacy/bar", false},
+		{"alternation in another folder", Project{Folder: "src/api", Patterns: []string{"foo|bar"}}, "src/web/bar.go", false},
+		{"alternation in command group", Project{Folder: "src/api", Commands: []CommandGroup{{Patterns: []string{"foo|bar"}, Cmd: "make"}}}, "src/web/bar.go", false},
+	}
+
+	for _, table := range tables {
+		assert.NoError(t, table.project.Check(), table.name)
+		assert.Equal(t, table.expected, table.project.Match(table.file), table.name)
+	}
+}
+
+func TestCheckGlobalTriggers(t *testing.T) {
+	config := Config{}
+	config.Input.GlobalTriggers = []string{"build/", "Directory.Build.props", "/eirctl.yaml", ".github/workflows/*.yml"}
+
+	assert.NoError(t, config.Check())
+
+	tables := []struct {
+		file     string
+		expected bool
+	}{
+		{"build/scripts/test.sh", true},
+		{"src/api/Directory.Build.props", true},
+		{"eirctl.yaml", true},
+		{"src/eirctl.yaml", false},
+		{".github/workflows/ci.yml", true},
+		{"src/api/main.go", false},
+	}
+
+	for _, table := range tables {
+		assert.Equal(t, table.expected, config.Input.IsGlobalTrigger(table.file), table.file)
+	}
+
+	config.Input.GlobalTriggers = []string{"!build/"}
+	assert.Error(t, config.Check(), "A negated global trigger should be reported")
+}
+
+func TestCheckDiscovery(t *testing.T) {
+
+	tables := []struct {
+		name  string
+		rule  DiscoveryRule
+		valid bool
+	}{
+		{"valid rule", DiscoveryRule{Marker: "go.mod", Cmd: "go test ./..."}, true},
+		{"wildcard marker", DiscoveryRule{Marker: "*.csproj", Cmd: "dotnet build {{ .Marker }}"}, true},
+		{"no marker", DiscoveryRule{Cmd: "go test ./..."}, false},
+		{"marker with folder", DiscoveryRule{Marker: "src/go.mod", Cmd: "go test ./..."}, false},
+		{"no command", DiscoveryRule{Marker: "go.mod"}, false},
+		{"invalid template", DiscoveryRule{Marker: "go.mod", Cmd: "go test {{ .Folder"}, false},
+	}
+
+	for _, table := range tables {
+		config := Config{}
+		config.Input.Discovery.Rules = []DiscoveryRule{table.rule}
+
+		assert.Equal(t, table.valid, config.Check() == nil, table.name)
+	}
+}
+
+func TestProjectGetCommands(t *testing.T) {
+
+	project := Project{
+		Name:     "api",
+		Folder:   "src/api",
+		Patterns: []string{".*"},
+		Build:    Build{Cmd: "taskctl build"},
+		Commands: []CommandGroup{
+			{Globs: []string{"**/*.cs"}, Cmd: "taskctl compile"},
+			{Globs: []string{"docs/**"}, Cmd: "taskctl docs"},
+			{Patterns: []string{"helm/.*"}, Cmd: "taskctl helm-lint"},
+			{Globs: []string{"**/*.csproj"}, Cmd: "taskctl compile"},
+		},
+	}
+
+	tables := []struct {
+		name     string
+		files    []string
+		expected []string
+	}{
+		{"single group", []string{"src/api/docs/index.md"}, []string{"taskctl docs"}},
+		{"several groups in declared order", []string{"src/api/helm/values.yaml", "src/api/Program.cs"}, []string{"taskctl compile", "taskctl helm-lint"}},
+		{"same command in more than one group", []string{"src/api/Program.cs", "src/api/api.csproj"}, []string{"taskctl compile"}},
+		{"file outside the groups", []string{"src/api/README.md", "src/api/docs/index.md"}, []string{"taskctl build", "taskctl docs"}},
+		{"no files", nil, []string{"taskctl build", "taskctl compile", "taskctl docs", "taskctl helm-lint"}},
+	}
+
+	assert.NoError(t, project.Check())
+
+	for _, table := range tables {
+		assert.Equal(t, table.expected, project.GetCommands(table.files), table.name)
+	}
+
+	// the files in a group also affect the project
+	project.Patterns = nil
+	assert.NoError(t, project.Check())
+	assert.True(t, project.Match("src/api/helm/Chart.yaml"))
+	assert.False(t, project.Match("src/api/README.md"))
+
+	// a project without groups runs the build command
+	assert.Equal(t, []string{"make"}, (&Project{Build: Build{Cmd: "make"}}).GetCommands([]string{"main.go"}))
+
+	project.Commands = append(project.Commands, CommandGroup{Cmd: "taskctl test"})
+	assert.Error(t, project.Check(), "A group without patterns should be reported")
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+)
+
+// Discovery holds the rules that are used to find projects in the repository from the
+// files that mark the folder of a project, such as go.mod or package.json
+type Discovery struct {
+	Exclude []string        `mapstructure:"exclude"` // Patterns for the marker files that should not be used to discover a project
+	Rules   []DiscoveryRule `mapstructure:"rules"`
+}
+
+// DiscoveryRule creates a project for each folder that contains the marker file
+// The name and the command are templates, which have access to the Name, Folder and
+// Marker of the project that has been discovered
+type DiscoveryRule struct {
+	Marker string            `mapstructure:"marker"` // Name of the file that marks the folder of a project, e.g. go.mod or *.csproj
+	Name   string            `mapstructure:"name"`   // Template for the name of the project, the folder is used if not set
+	Cmd    string            `mapstructure:"cmd"`    // Template for the command to build the project
+	Globs  []string          `mapstructure:"globs"`  // Glob patterns for the files in the project, all files are used if not set
+	Env    map[string]string `mapstructure:"env"`
+	Order  int               `mapstructure:"order"`
+}
+
+// TemplateData holds the values that can be used in the templates of a discovery rule,
+// and in the values that a project inherits from the defaults and templates
+type TemplateData struct {
+	Name   string // Name of the project, this is the folder when rendering the name of a discovered project
+	Folder string // Folder of the project, relative to the root of the repository
+	Marker string // Name of the marker file that was found, if the project was discovered
+}
+
+// Enabled states if any rules have been set so that projects should be discovered
+func (d *Discovery) Enabled() bool {
+	return len(d.Rules) > 0
+}
+
+// Check ensures that each rule has a valid marker and that the templates can be parsed
+func (d *Discovery) Check() error {
+
+	for i := range d.Rules {
+		rule := &d.Rules[i]
+		rule.Marker = strings.TrimSpace(rule.Marker)
+
+		if rule.Marker == "" || strings.Contains(rule.Marker, "/") {
+			return fmt.Errorf("discovery rule %d must have a marker that is the name of a file", i+1)
+		}
+
+		if _, err := path.Match(rule.Marker, ""); err != nil {
+			return fmt.Errorf("invalid discovery marker '%s': %s", rule.Marker, err.Error())
+		}
+
+		if strings.TrimSpace(rule.Cmd) == "" {
+			return fmt.Errorf("discovery rule for '%s' must have a command", rule.Marker)
+		}
+
+		for _, text := range []string{rule.Name, rule.Cmd} {
+			if _, err := template.New(rule.Marker).Parse(text); err != nil {
+				return fmt.Errorf("invalid template in discovery rule for '%s': %s", rule.Marker, err.Error())
+			}
+		}
+	}
+
+	return nil
+}
+
+// Matches states if the file name is the marker of the rule
+func (r *DiscoveryRule) Matches(name string) bool {
+	ok, _ := path.Match(r.Marker, name)
+	return ok
+}
+
+// Project creates the project for the folder in which the marker file was found
+func (r *DiscoveryRule) Project(folder string, marker string) (Project, error) {
+
+	data := TemplateData{
+		Name:   folder,
+		Folder: folder,
+		Marker: marker,
+	}
+
+	name, err := render(r.Name, data)
+	if err != nil {
+		return Project{}, err
+	}
+
+	if name != "" {
+		data.Name = name
+	}
+
+	cmd, err := render(r.Cmd, data)
+	if err != nil {
+		return Project{}, err
+	}
+
+	globs := r.Globs
+	if len(globs) == 0 {
+		globs = []string{"**"}
+	}
+
+	return Project{
+		Name:       data.Name,
+		Folder:     folder,
+		Globs:      globs,
+		Build:      Build{Cmd: cmd},
+		Env:        r.Env,
+		Order:      r.Order,
+		Discovered: path.Join(folder, marker),
+	}, nil
+}
+
+// render executes the template with the data of the project
+func render(text string, data TemplateData) (string, error) {
+
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	tmpl, err := template.New("project").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var result bytes.Buffer
+	if err = tmpl.Execute(&result, data); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(result.String()), nil
+}
@@ -0,0 +1,87 @@
+package config
+
+import "github.com/amido/mrbuild/internal/match"
+
+const (
+	// PatternKindRegex is a regular expression from the patterns of the project
+	PatternKindRegex = "pattern"
+
+	// PatternKindGlob is a glob from the globs of the project
+	PatternKindGlob = "glob"
+
+	// PatternKindInput is a glob from the inputs of the project
+	PatternKindInput = "input"
+
+	// PatternKindExclude is a glob from the exclude patterns of the project
+	PatternKindExclude = "exclude"
+
+	// PatternKindCommand is a regular expression or glob from a group of commands
+	PatternKindCommand = "command"
+)
+
+// PatternResult states if one of the patterns of a project matches a file
+type PatternResult struct {
+	Kind       string // Kind of the pattern, e.g. pattern, glob, input, exclude or command
+	Pattern    string // Regular expression as it is set in the configuration, globs are only shown as generated
+	Expression string // Regular expression or glob, including the folder, that is matched against the file
+	Command    string // Command of the group, for the patterns of a group of commands
+	Matched    bool
+}
+
+// Explain returns each of the patterns of the project and if it matches the file, so
+// that it can be seen why a file does or does not belong to the project
+// The full regular expression is returned, which includes the folder of the project
+func (p *Project) Explain(file string) []PatternResult {
+
+	var results []PatternResult
+
+	matcher := p.getMatcher()
+	if matcher == nil {
+		return results
+	}
+
+	results = append(results, matcher.explain(file, PatternKindRegex, PatternKindGlob, p.Patterns, "")...)
+	results = append(results, explainGlobs(PatternKindInput, matcher.inputs, file, "")...)
+
+	for i, group := range matcher.groups {
+		results = append(results, group.explain(file, PatternKindCommand, PatternKindCommand, p.Commands[i].Patterns, p.Commands[i].Cmd)...)
+	}
+
+	return append(results, explainGlobs(PatternKindExclude, matcher.exclude, file, "")...)
+}
+
+// explain returns the result of each of the regular expressions, which were compiled from
+// the patterns, and each of the globs
+func (m *projectMatcher) explain(file string, regexKind string, globKind string, patterns []string, command string) []PatternResult {
+
+	var results []PatternResult
+
+	for i, re := range m.patterns {
+		results = append(results, PatternResult{
+			Kind:       regexKind,
+			Pattern:    patterns[i],
+			Expression: re.String(),
+			Command:    command,
+			Matched:    re.MatchString(file),
+		})
+	}
+
+	return append(results, explainGlobs(globKind, m.globs, file, command)...)
+}
+
+// explainGlobs returns the result of each of the globs
+func explainGlobs(kind string, globs *match.Globs, file string, command string) []PatternResult {
+
+	var results []PatternResult
+
+	for _, rule := range globs.Explain(file) {
+		results = append(results, PatternResult{
+			Kind:       kind,
+			Expression: rule.Pattern,
+			Command:    command,
+			Matched:    rule.Matched,
+		})
+	}
+
+	return results
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProjectExplain(t *testing.T) {
+
+	project := Project{
+		Name:     "api",
+		Folder:   "src/api",
+		Patterns: []string{".*\\.go"},
+		Globs:    []string{"**/*.yaml"},
+		Inputs:   []string{"libs/common"},
+		Exclude:  []string{"**/*_test.go"},
+		Commands: []CommandGroup{
+			{Globs: []string{"docs/**"}, Cmd: "docs"},
+		},
+	}
+
+	assert.NoError(t, project.Check())
+
+	tables := []struct {
+		name     string
+		file     string
+		expected []PatternResult
+	}{
+		{
+			"pattern",
+			"src/api/main.go",
+			[]PatternResult{
+				{Kind: PatternKindRegex, Pattern: ".*\\.go", Expression: "^src/api/(?:.*\\.go)", Matched: true},
+				{Kind: PatternKindGlob, Expression: "src/api/**/*.yaml"},
+				{Kind: PatternKindInput, Expression: "libs/common"},
+				{Kind: PatternKindInput, Expression: "libs/common/**"},
+				{Kind: PatternKindCommand, Expression: "src/api/docs/**", Command: "docs"},
+				{Kind: PatternKindExclude, Expression: "src/api/**/*_test.go"},
+			},
+		},
+		{
+			"input",
+			"libs/common/util.go",
+			[]PatternResult{
+				{Kind: PatternKindRegex, Pattern: ".*\\.go", Expression: "^src/api/(?:.*\\.go)"},
+				{Kind: PatternKindGlob, Expression: "src/api/**/*.yaml"},
+				{Kind: PatternKindInput, Expression: "libs/common"},
+				{Kind: PatternKindInput, Expression: "libs/common/**", Matched: true},
+				{Kind: PatternKindCommand, Expression: "src/api/docs/**", Command: "docs"},
+				{Kind: PatternKindExclude, Expression: "src/api/**/*_test.go"},
+			},
+		},
+	}
+
+	for _, table := range tables {
+		assert.Equal(t, table.expected, project.Explain(table.file), table.name)
+	}
+}
@@ -1,5 +1,7 @@
 package config
 
+import "github.com/amido/mrbuild/internal/match"
+
 type InputConfig struct {
 	Config string `mapstructure:"config"`
 
@@ -9,8 +11,26 @@ type InputConfig struct {
 	Directory Directory `mapstructure:"directory"`
 	Log       Log       `mapstructure:"log"`
 	Projects  []Project `mapstructure:"projects"`
-	Pool      Pool      `mapstructure:"pool"`
-	Branch    string    `mapstructure:"branch"` // Branch that changes should be measured against
-	Options   Options   `mapstructure:"options"`
-	Datafile  string    `mapstructure:"datafile"`
+
+	// settings that are applied to every project, and named templates that a project can extend
+	Defaults  Project            `mapstructure:"defaults"`
+	Templates map[string]Project `mapstructure:"templates"`
+	Discovery Discovery          `mapstructure:"discovery"`
+
+	// patterns for files that affect every project when they change
+	GlobalTriggers []string `mapstructure:"global_triggers"`
+	Pool           Pool     `mapstructure:"pool"`
+	Branch         string   `mapstructure:"branch"` // Branch that changes should be measured against
+	Compare        Compare  `mapstructure:"compare"`
+	Shallow        Shallow  `mapstructure:"shallow"`
+	Snapshot       Snapshot `mapstructure:"snapshot"`
+	Baseline       Baseline `mapstructure:"baseline"`
+	Options        Options  `mapstructure:"options"`
+	Datafile       string   `mapstructure:"datafile"`
+
+	// Format of the data in the datafile or from the pipe
+	InputFormat string `mapstructure:"inputformat"`
+
+	// global trigger patterns that have been compiled
+	globalTriggers *match.Globs
 }
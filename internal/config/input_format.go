@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// InputFormatLines is a newline delimited list of files, optionally with the status from `git diff --name-status`
+	InputFormatLines = "lines"
+
+	// InputFormatNul is the NUL delimited output of `git diff -z`
+	InputFormatNul = "nul"
+
+	// InputFormatPorcelain is the output of `git status --porcelain=v1`
+	InputFormatPorcelain = "porcelain"
+
+	// InputFormatPorcelainV2 is the output of `git status --porcelain=v2`
+	InputFormatPorcelainV2 = "porcelain-v2"
+
+	// InputFormatJSON is a JSON array of paths
+	InputFormatJSON = "json"
+
+	// InputFormatGitHub is the JSON from the GitHub "list pull request files" API
+	InputFormatGitHub = "github"
+
+	// InputFormatGitLab is the JSON from the GitLab merge request changes API
+	InputFormatGitLab = "gitlab"
+)
+
+// InputFormats is the list of formats that the datafile or piped data can be in
+var InputFormats = []string{
+	InputFormatLines,
+	InputFormatNul,
+	InputFormatPorcelain,
+	InputFormatPorcelainV2,
+	InputFormatJSON,
+	InputFormatGitHub,
+	InputFormatGitLab,
+}
+
+// CheckInputFormat ensures that the format of the input data is valid
+// If no format has been set then the lines format is used
+func (ic *InputConfig) CheckInputFormat() error {
+	ic.InputFormat = strings.ToLower(strings.TrimSpace(ic.InputFormat))
+
+	if ic.InputFormat == "" {
+		ic.InputFormat = InputFormatLines
+	}
+
+	for _, format := range InputFormats {
+		if ic.InputFormat == format {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown input format '%s', must be one of %s", ic.InputFormat, strings.Join(InputFormats, ", "))
+}
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"fmt"
+	"regexp"
 	"strings"
 
 	"github.com/amido/mrbuild/internal/util"
@@ -8,9 +10,59 @@ import (
 
 // Options holds the options for the CLI, such as turning on cmd logging
 type Options struct {
-	CmdLog bool   `mapstructure:"cmdlog"`
-	DryRun bool   `mapstructure:"dryrun"`
-	Ignore string `mapstructure:"ignore"`
+	CmdLog       bool   `mapstructure:"cmdlog"`
+	DryRun       bool   `mapstructure:"dryrun"`
+	Ignore       string `mapstructure:"ignore"`
+	NoDirectives bool   `mapstructure:"nodirectives"` // Do not read directives from the commit message or pull request labels
+	OnlyTags     string `mapstructure:"onlytags"`     // Comma delimited list of tags, only projects with one of the tags are built
+	SkipTags     string `mapstructure:"skiptags"`     // Comma delimited list of tags, projects with any of the tags are not built
+	Only         string `mapstructure:"only"`         // Regular expression, only projects with a name that matches are built
+}
+
+// Check ensures that the regular expression to select the projects is valid
+func (o *Options) Check() error {
+
+	if _, err := regexp.Compile(o.Only); err != nil {
+		return fmt.Errorf("invalid only pattern '%s': %s", o.Only, err.Error())
+	}
+
+	return nil
+}
+
+// SelectProject determines if the affected project has been selected to be built by the
+// tags and name selectors. If it has not, the reason is returned
+func (o *Options) SelectProject(project Project) (bool, string) {
+
+	if tags := splitList(o.OnlyTags); len(tags) > 0 && !project.HasTag(tags...) {
+		return false, fmt.Sprintf("does not have any of the tags %s", strings.Join(tags, ", "))
+	}
+
+	if tags := splitList(o.SkipTags); len(tags) > 0 && project.HasTag(tags...) {
+		return false, fmt.Sprintf("has one of the tags %s", strings.Join(tags, ", "))
+	}
+
+	// the whole of the name must match
+	if o.Only != "" {
+		if ok, _ := regexp.MatchString("^(?:"+o.Only+")$", project.Name); !ok {
+			return false, fmt.Sprintf("has a name that does not match '%s'", o.Only)
+		}
+	}
+
+	return true, ""
+}
+
+// splitList splits the comma delimited list, removing any empty items
+func splitList(list string) []string {
+
+	var items []string
+
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
 }
 
 func (o *Options) IgnoreProject(project string) bool {
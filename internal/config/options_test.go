@@ -44,3 +44,43 @@ func TestIgnoreProject(t *testing.T) {
 
 	}
 }
+
+func TestSelectProject(t *testing.T) {
+
+	projects := []Project{
+		{Name: "network", Tags: []string{"infra"}},
+		{Name: "api", Tags: []string{"dotnet", "apps"}},
+		{Name: "web", Tags: []string{"Frontend", "apps"}},
+		{Name: "docs"},
+	}
+
+	testCases := []struct {
+		name     string
+		options  Options
+		expected []bool
+	}{
+		{"no selectors", Options{}, []bool{true, true, true, true}},
+		{"only tags", Options{OnlyTags: "infra"}, []bool{true, false, false, false}},
+		{"only multiple tags", Options{OnlyTags: "dotnet, frontend"}, []bool{false, true, true, false}},
+		{"skip tags", Options{SkipTags: "apps"}, []bool{true, false, false, true}},
+		{"only and skip tags", Options{OnlyTags: "apps", SkipTags: "frontend"}, []bool{false, true, false, false}},
+		{"only name", Options{Only: "api|web"}, []bool{false, true, true, false}},
+		{"only name is anchored", Options{Only: "we"}, []bool{false, false, false, false}},
+		{"only name and tags", Options{Only: ".*", OnlyTags: "infra"}, []bool{true, false, false, false}},
+	}
+
+	for _, testCase := range testCases {
+		for idx, project := range projects {
+			result, _ := testCase.options.SelectProject(project)
+
+			if result != testCase.expected[idx] {
+				t.Errorf("%s: selection of '%s' does not equal %v", testCase.name, project.Name, testCase.expected[idx])
+			}
+		}
+	}
+
+	options := Options{Only: "api("}
+	if options.Check() == nil {
+		t.Error("An invalid only pattern should be reported")
+	}
+}
@@ -1,10 +1,257 @@
 package config
 
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/amido/mrbuild/internal/match"
+)
+
 type Project struct {
-	Name     string            `mapstructure:"name"`
-	Folder   string            `mapstructure:"folder"`
-	Patterns []string          `mapstructure:"patterns"`
-	Build    Build             `mapstructure:"build"` // Command to run if the directory contents have changed
-	Env      map[string]string `mapstructure:"env"`   // list of environment variables that should be set when the command is executed
-	Order    int               `mapstructure:"order"` // Order in which the project should be run.
+	Name            string            `mapstructure:"name"`
+	Folder          string            `mapstructure:"folder"`
+	Patterns        []string          `mapstructure:"patterns"`
+	Globs           []string          `mapstructure:"globs"`            // Glob patterns, relative to the folder, a pattern starting with ! excludes files
+	Exclude         []string          `mapstructure:"exclude"`          // Patterns for files that never affect the project, even if they match
+	Inputs          []string          `mapstructure:"inputs"`           // Patterns, relative to the root of the repository, for files outside the folder that affect the project
+	Build           Build             `mapstructure:"build"`            // Command to run if the directory contents have changed
+	Commands        []CommandGroup    `mapstructure:"commands"`         // Commands to run when the files that match a group of patterns have changed
+	Env             map[string]string `mapstructure:"env"`              // list of environment variables that should be set when the command is executed
+	Order           int               `mapstructure:"order"`            // Order in which the project should be run.
+	OnDelete        string            `mapstructure:"on_delete"`        // Command to run if the project folder has been removed
+	IgnoreTrivial   bool              `mapstructure:"ignore_trivial"`   // Do not build the project if the only changes are to whitespace or comments
+	CommentPatterns []string          `mapstructure:"comment_patterns"` // Regular expressions that match comment lines, used by ignore_trivial
+	TriggersAll     bool              `mapstructure:"triggers_all"`     // A change to the project affects every project
+	DependsOn       []string          `mapstructure:"depends_on"`       // Names of the projects that this project depends on
+	Tags            []string          `mapstructure:"tags"`             // Tags that can be used to select the projects to build, e.g. infra or frontend
+	Extends         string            `mapstructure:"extends"`          // Name of the template that the project is based on
+	Discovered      string            `mapstructure:"-"`                // Path to the marker file if the project was discovered rather than configured
+
+	// patterns, globs and exclusions compiled with the folder of the project
+	matcher *projectMatcher
+
+	// states if the defaults and templates have been applied to the project
+	resolved bool
+
+	// settings that were set in the configuration file, even if they were set to the zero value
+	explicit map[string]bool
+}
+
+// projectMatcher holds the compiled patterns that determine which files belong to a project
+type projectMatcher struct {
+	patterns []*regexp.Regexp
+	globs    *match.Globs
+	inputs   *match.Globs
+	exclude  *match.Globs
+	groups   []*projectMatcher
+}
+
+// CommandGroup maps a group of patterns, relative to the folder of the project, to the
+// command that should be run when any of the files that match them have changed
+type CommandGroup struct {
+	Patterns []string `mapstructure:"patterns"` // Regular expressions, relative to the folder
+	Globs    []string `mapstructure:"globs"`    // Glob patterns, relative to the folder
+	Cmd      string   `mapstructure:"cmd"`
+}
+
+// Check ensures that the patterns of the project are valid and compiles them so that
+// they are only compiled once
+func (p *Project) Check() error {
+	var err error
+
+	p.matcher, err = p.compile()
+	if err != nil {
+		return err
+	}
+
+	for _, pattern := range p.CommentPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid comment pattern '%s' for project %s: %s", pattern, p.Name, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// Match determines if the file, with a path relative to the root of the repository, belongs
+// to the project. The file must match one of the patterns, globs, inputs or command groups
+// and not be excluded
+// The regular expressions are anchored to the start of the path, so they must match the
+// folder of the project and then the pattern
+func (p *Project) Match(file string) bool {
+
+	matcher := p.getMatcher()
+	if matcher == nil || file == "" || (!matcher.exclude.Empty() && matcher.exclude.Match(file)) {
+		return false
+	}
+
+	if matcher.match(file) || (!matcher.inputs.Empty() && matcher.inputs.Match(file)) {
+		return true
+	}
+
+	for _, group := range matcher.groups {
+		if group.match(file) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetCommands returns the commands that should be run for the files that have changed
+// The command of each group that matches one of the files is run, in the order in which
+// the groups are declared. The build command is run first if any file does not match a
+// group. If there are no files, because the project is being built regardless of the
+// changes, all of the commands are run
+func (p *Project) GetCommands(files []string) []string {
+
+	var commands []string
+
+	add := func(command string) {
+		if command = strings.TrimSpace(command); command == "" {
+			return
+		}
+
+		for _, item := range commands {
+			if item == command {
+				return
+			}
+		}
+
+		commands = append(commands, command)
+	}
+
+	matcher := p.getMatcher()
+	if matcher == nil || len(matcher.groups) == 0 {
+		add(p.Build.Cmd)
+		return commands
+	}
+
+	matched := make([]bool, len(p.Commands))
+	ungrouped := len(files) == 0
+
+	for _, file := range files {
+		found := false
+
+		for i, group := range matcher.groups {
+			if group.match(file) {
+				matched[i] = true
+				found = true
+			}
+		}
+
+		ungrouped = ungrouped || !found
+	}
+
+	if ungrouped {
+		add(p.Build.Cmd)
+	}
+
+	for i, group := range p.Commands {
+		if matched[i] || len(files) == 0 {
+			add(group.Cmd)
+		}
+	}
+
+	return commands
+}
+
+// getMatcher returns the compiled patterns of the project, compiling them if the project
+// has not been checked. Nil is returned if the patterns are not valid
+func (p *Project) getMatcher() *projectMatcher {
+
+	if p.matcher != nil {
+		return p.matcher
+	}
+
+	matcher, err := p.compile()
+	if err != nil {
+		return nil
+	}
+
+	return matcher
+}
+
+// match determines if the file matches one of the patterns or globs
+func (m *projectMatcher) match(file string) bool {
+
+	for _, re := range m.patterns {
+		if re.MatchString(file) {
+			return true
+		}
+	}
+
+	return !m.globs.Empty() && m.globs.Match(file)
+}
+
+// HasTag states if the project has any of the tags, the comparison is not case sensitive
+func (p *Project) HasTag(tags ...string) bool {
+
+	for _, tag := range tags {
+		for _, item := range p.Tags {
+			if strings.EqualFold(strings.TrimSpace(item), tag) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// compile compiles the patterns, globs, inputs and exclusions of the project
+func (p *Project) compile() (*projectMatcher, error) {
+	var err error
+
+	matcher := &projectMatcher{}
+
+	// the folder is matched literally, the root of the repository does not need a prefix
+	prefix := "^"
+	if folder := path.Clean(strings.ReplaceAll(p.Folder, "\\", "/")); folder != "." && folder != "/" {
+		prefix = fmt.Sprintf("^%s/", regexp.QuoteMeta(strings.Trim(folder, "/")))
+	}
+
+	// the pattern is grouped so that an alternation cannot escape the folder, e.g. foo|bar
+	for _, pattern := range p.Patterns {
+		re, err := regexp.Compile(prefix + "(?:" + pattern + ")")
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern '%s' for project %s: %s", pattern, p.Name, err.Error())
+		}
+
+		matcher.patterns = append(matcher.patterns, re)
+	}
+
+	matcher.globs, err = match.CompileGlobs(p.Folder, p.Globs)
+	if err != nil {
+		return nil, fmt.Errorf("%s for project %s", err.Error(), p.Name)
+	}
+
+	matcher.inputs, err = match.CompileInputs(p.Inputs)
+	if err != nil {
+		return nil, fmt.Errorf("%s for project %s", err.Error(), p.Name)
+	}
+
+	matcher.exclude, err = match.CompileExcludes(p.Folder, p.Exclude)
+	if err != nil {
+		return nil, fmt.Errorf("%s for project %s", err.Error(), p.Name)
+	}
+
+	// each group of commands is compiled in the same way as the patterns of a project
+	for i, command := range p.Commands {
+		if strings.TrimSpace(command.Cmd) == "" || len(command.Patterns)+len(command.Globs) == 0 {
+			return nil, fmt.Errorf("command group %d for project %s must have a cmd and at least one pattern or glob", i+1, p.Name)
+		}
+
+		group := Project{Name: p.Name, Folder: p.Folder, Patterns: command.Patterns, Globs: command.Globs}
+
+		groupMatcher, err := group.compile()
+		if err != nil {
+			return nil, err
+		}
+
+		matcher.groups = append(matcher.groups, groupMatcher)
+	}
+
+	return matcher, nil
 }
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// ShallowFallbackFail stops with an error if the changes cannot be determined in a shallow clone
+	ShallowFallbackFail = "fail"
+
+	// ShallowFallbackAll builds all of the projects if the changes cannot be determined in a shallow clone
+	ShallowFallbackAll = "all"
+)
+
+// Shallow holds the settings for when the repository is a shallow clone and the
+// commits that are required to determine the changes are not available
+type Shallow struct {
+	Deepen   int    `mapstructure:"deepen"`   // Number of commits to deepen the history by at each step, 0 disables deepening
+	Max      int    `mapstructure:"max"`      // Maximum number of commits to deepen the history by
+	Fallback string `mapstructure:"fallback"` // What to do if the changes cannot be determined, fail or all
+}
+
+// Check ensures that the fallback policy is valid and sets the defaults
+func (s *Shallow) Check() error {
+	s.Fallback = strings.ToLower(strings.TrimSpace(s.Fallback))
+
+	switch s.Fallback {
+	case "":
+		s.Fallback = ShallowFallbackFail
+	case ShallowFallbackFail, ShallowFallbackAll:
+	default:
+		return fmt.Errorf("unknown shallow fallback '%s', must be one of %s or %s", s.Fallback, ShallowFallbackFail, ShallowFallbackAll)
+	}
+
+	if s.Deepen < 0 || s.Max < 0 {
+		return fmt.Errorf("deepen and max must not be negative")
+	}
+
+	// if deepening has been enabled without a maximum, allow for 10 steps
+	if s.Deepen > 0 && s.Max == 0 {
+		s.Max = s.Deepen * 10
+	}
+
+	return nil
+}
@@ -0,0 +1,24 @@
+package config
+
+import "strings"
+
+// DefaultSnapshotManifest is the path to the manifest that is used if one has not been set
+const DefaultSnapshotManifest = ".mrbuild-snapshot.json"
+
+// Snapshot holds the settings for detecting changes by comparing the hashes of the files
+// in each project with a manifest, rather than reading the git history
+type Snapshot struct {
+	Enabled  bool   `mapstructure:"enabled"`  // Use the snapshot to determine the changes instead of git
+	Manifest string `mapstructure:"manifest"` // Path to the manifest saved by the previous successful run
+}
+
+// Check ensures that the path to the manifest has been set
+func (s *Snapshot) Check() error {
+	s.Manifest = strings.TrimSpace(s.Manifest)
+
+	if s.Manifest == "" {
+		s.Manifest = DefaultSnapshotManifest
+	}
+
+	return nil
+}
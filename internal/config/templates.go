@@ -0,0 +1,252 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ResolveProjects applies the defaults, and the templates that each project extends, to
+// each of the projects so that the rest of the application works with the merged settings
+func (ic *InputConfig) ResolveProjects() error {
+
+	for i := range ic.Projects {
+		project, err := ic.ResolveProject(ic.Projects[i])
+		if err != nil {
+			return err
+		}
+
+		ic.Projects[i] = project
+	}
+
+	return nil
+}
+
+// ResolveProject merges the defaults, the chain of templates that the project extends
+// and then the project itself, with the later settings taking precedence. The env of
+// each is merged so that only the variables that are set are overridden
+// The inherited settings can use the name and folder of the project in a template, e.g.
+// {{ .Name }}, and are evaluated for each project
+func (ic *InputConfig) ResolveProject(project Project) (Project, error) {
+
+	if project.resolved {
+		return project, nil
+	}
+
+	layers, err := ic.getTemplates(project)
+	if err != nil {
+		return project, err
+	}
+
+	data := TemplateData{
+		Name:   project.Name,
+		Folder: project.Folder,
+	}
+
+	// the folder may be inherited, in which case it can be based on the name of the project
+	for i := len(layers) - 1; i >= 0 && data.Folder == ""; i-- {
+		if data.Folder, err = render(layers[i].Folder, data); err != nil {
+			return project, fmt.Errorf("unable to set the folder of project %s: %s", project.Name, err.Error())
+		}
+	}
+
+	var result Project
+	for _, layer := range layers {
+		if layer, err = layer.render(data); err != nil {
+			return project, fmt.Errorf("unable to apply template to project %s: %s", project.Name, err.Error())
+		}
+
+		result = mergeProject(result, layer)
+	}
+
+	result = mergeProject(result, project)
+	result.Folder = data.Folder
+	result.Extends = project.Extends
+	result.resolved = true
+
+	return result, nil
+}
+
+// getTemplates returns the defaults, followed by the chain of templates that the project
+// extends, starting with the template that is furthest away
+func (ic *InputConfig) getTemplates(project Project) ([]Project, error) {
+
+	var chain []Project
+	seen := make(map[string]bool)
+
+	for name := project.Extends; name != ""; {
+		if seen[name] {
+			return nil, fmt.Errorf("templates extended by project %s form a cycle at %s", project.Name, name)
+		}
+		seen[name] = true
+
+		template, ok := ic.Templates[name]
+		if !ok {
+			return nil, fmt.Errorf("project %s extends unknown template %s", project.Name, name)
+		}
+
+		chain = append([]Project{template}, chain...)
+		name = template.Extends
+	}
+
+	return append([]Project{ic.Defaults}, chain...), nil
+}
+
+// render evaluates the settings of a default or template for the project
+func (p Project) render(data TemplateData) (Project, error) {
+	var err error
+
+	for _, value := range []*string{&p.Build.Cmd, &p.Build.Folder, &p.OnDelete} {
+		if *value, err = render(*value, data); err != nil {
+			return p, err
+		}
+	}
+
+	if len(p.Commands) > 0 {
+		commands := make([]CommandGroup, len(p.Commands))
+
+		for i, command := range p.Commands {
+			commands[i] = command
+			if commands[i].Cmd, err = render(command.Cmd, data); err != nil {
+				return p, err
+			}
+		}
+
+		p.Commands = commands
+	}
+
+	if len(p.Env) > 0 {
+		env := make(map[string]string)
+
+		for name, value := range p.Env {
+			if env[name], err = render(value, data); err != nil {
+				return p, err
+			}
+		}
+
+		p.Env = env
+	}
+
+	return p, nil
+}
+
+// mergeProject returns the base project with the settings that have been set in the
+// override applied. Lists replace those in the base, whereas the env is merged
+func mergeProject(base Project, override Project) Project {
+
+	result := base
+
+	for _, item := range []struct {
+		target *string
+		value  string
+	}{
+		{&result.Name, override.Name},
+		{&result.Folder, override.Folder},
+		{&result.Build.Cmd, override.Build.Cmd},
+		{&result.Build.Folder, override.Build.Folder},
+		{&result.OnDelete, override.OnDelete},
+		{&result.Discovered, override.Discovered},
+	} {
+		if item.value != "" {
+			*item.target = item.value
+		}
+	}
+
+	for _, item := range []struct {
+		target *[]string
+		value  []string
+	}{
+		{&result.Patterns, override.Patterns},
+		{&result.Globs, override.Globs},
+		{&result.Exclude, override.Exclude},
+		{&result.Inputs, override.Inputs},
+		{&result.CommentPatterns, override.CommentPatterns},
+		{&result.DependsOn, override.DependsOn},
+		{&result.Tags, override.Tags},
+	} {
+		if item.value != nil {
+			*item.target = item.value
+		}
+	}
+
+	if override.Commands != nil {
+		result.Commands = override.Commands
+	}
+
+	// a setting that has been set explicitly is used, even if it is the zero value, so
+	// that a project can turn off a setting that has been set by a template
+	if override.Order != 0 || override.isSet("order") {
+		result.Order = override.Order
+	}
+
+	if override.IgnoreTrivial || override.isSet("ignore_trivial") {
+		result.IgnoreTrivial = override.IgnoreTrivial
+	}
+
+	if override.TriggersAll || override.isSet("triggers_all") {
+		result.TriggersAll = override.TriggersAll
+	}
+
+	if len(base.Env) > 0 || len(override.Env) > 0 {
+		result.Env = make(map[string]string)
+
+		for name, value := range base.Env {
+			result.Env[name] = value
+		}
+
+		for name, value := range override.Env {
+			result.Env[name] = value
+		}
+	}
+
+	return result
+}
+
+// SetKeys records which settings of the defaults, templates and projects were set in the
+// configuration, using the keys of the metadata from when it was decoded, e.g. projects[0].order
+// This is so that a setting that has been set to the zero value still overrides the value
+// that is inherited from a template
+func (ic *InputConfig) SetKeys(keys []string) {
+
+	for _, key := range keys {
+		index := strings.LastIndex(key, ".")
+		if index < 0 {
+			continue
+		}
+
+		parent, setting := key[:index], key[index+1:]
+
+		switch {
+		case parent == "defaults":
+			ic.Defaults.setExplicitly(setting)
+
+		case strings.HasPrefix(parent, "projects[") && strings.HasSuffix(parent, "]"):
+			i, err := strconv.Atoi(parent[len("projects[") : len(parent)-1])
+			if err == nil && i >= 0 && i < len(ic.Projects) {
+				ic.Projects[i].setExplicitly(setting)
+			}
+
+		case strings.HasPrefix(parent, "templates[") && strings.HasSuffix(parent, "]"):
+			name := parent[len("templates[") : len(parent)-1]
+			if template, ok := ic.Templates[name]; ok {
+				template.setExplicitly(setting)
+				ic.Templates[name] = template
+			}
+		}
+	}
+}
+
+// setExplicitly records that the setting was set in the configuration
+func (p *Project) setExplicitly(setting string) {
+
+	if p.explicit == nil {
+		p.explicit = make(map[string]bool)
+	}
+
+	p.explicit[setting] = true
+}
+
+// isSet states if the setting was set in the configuration, even if it was set to the zero value
+func (p *Project) isSet(setting string) bool {
+	return p.explicit[setting]
+}
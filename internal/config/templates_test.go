@@ -0,0 +1,113 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveProjects(t *testing.T) {
+
+	input := InputConfig{
+		Defaults: Project{
+			Patterns: []string{".*"},
+			Env:      map[string]string{"region": "uksouth", "stage": "dev"},
+			Build:    Build{Cmd: "taskctl build {{ .Name }}"},
+		},
+		Templates: map[string]Project{
+			"dotnet": {
+				Folder: "src/{{ .Name }}",
+				Tags:   []string{"dotnet"},
+				Env:    map[string]string{"stage": "test"},
+			},
+			"api": {
+				Extends: "dotnet",
+				Build:   Build{Cmd: "dotnet build {{ .Folder }}"},
+				Order:   1,
+			},
+		},
+		Projects: []Project{
+			{Name: "lib", Folder: "libs/common"},
+			{Name: "billing", Extends: "api", Env: map[string]string{"team": "payments"}},
+			{Name: "orders", Folder: "services/orders", Extends: "api", Patterns: []string{".*\\.cs"}, Build: Build{Cmd: "make {{ .Name }}"}},
+		},
+	}
+
+	assert.NoError(t, input.ResolveProjects())
+
+	lib := input.Projects[0]
+	assert.Equal(t, "taskctl build lib", lib.Build.Cmd)
+	assert.Equal(t, []string{".*"}, lib.Patterns)
+	assert.Equal(t, map[string]string{"region": "uksouth", "stage": "dev"}, lib.Env)
+
+	billing := input.Projects[1]
+	assert.Equal(t, "src/billing", billing.Folder, "The folder should be evaluated for the project")
+	assert.Equal(t, "dotnet build src/billing", billing.Build.Cmd)
+	assert.Equal(t, map[string]string{"region": "uksouth", "stage": "test", "team": "payments"}, billing.Env, "The env should be merged")
+	assert.Equal(t, []string{"dotnet"}, billing.Tags)
+	assert.Equal(t, 1, billing.Order)
+	assert.True(t, billing.Match("src/billing/Program.cs"))
+
+	orders := input.Projects[2]
+	assert.Equal(t, "services/orders", orders.Folder)
+	assert.Equal(t, []string{".*\\.cs"}, orders.Patterns, "A list in the project should replace the inherited list")
+	assert.Equal(t, "make {{ .Name }}", orders.Build.Cmd, "The settings of the project should not be evaluated")
+
+	// resolving the projects again should not change them
+	assert.NoError(t, input.ResolveProjects())
+	assert.Equal(t, billing, input.Projects[1])
+
+	// the templates must exist and must not form a cycle
+	input.Projects = []Project{{Name: "web", Extends: "frontend"}}
+	assert.EqualError(t, input.ResolveProjects(), "project web extends unknown template frontend")
+
+	input.Templates["a"] = Project{Extends: "b"}
+	input.Templates["b"] = Project{Extends: "a"}
+	input.Projects = []Project{{Name: "web", Extends: "a"}}
+	assert.EqualError(t, input.ResolveProjects(), "templates extended by project web form a cycle at a")
+}
+
+func TestResolveProjectsExplicitZeroValues(t *testing.T) {
+
+	input := InputConfig{
+		Defaults: Project{
+			IgnoreTrivial: true,
+		},
+		Templates: map[string]Project{
+			"shared": {
+				TriggersAll: true,
+				Order:       3,
+			},
+		},
+		Projects: []Project{
+			{Name: "lib", Folder: "libs/common", Extends: "shared"},
+			{Name: "api", Folder: "src/api", Extends: "shared"},
+		},
+	}
+
+	// the keys as they are recorded when the configuration is decoded, the settings of api are set to the zero value
+	input.SetKeys([]string{
+		"defaults.ignore_trivial",
+		"templates[shared].triggers_all",
+		"templates[shared].order",
+		"projects[0].name",
+		"projects[1].name",
+		"projects[1].ignore_trivial",
+		"projects[1].triggers_all",
+		"projects[1].order",
+		"projects[2].order",
+		"templates[unknown].order",
+	})
+
+	assert.NoError(t, input.ResolveProjects())
+
+	lib := input.Projects[0]
+	assert.True(t, lib.IgnoreTrivial)
+	assert.True(t, lib.TriggersAll)
+	assert.Equal(t, 3, lib.Order)
+
+	api := input.Projects[1]
+	assert.False(t, api.IgnoreTrivial, "The project should be able to turn off an inherited setting")
+	assert.False(t, api.TriggersAll, "The project should be able to turn off an inherited setting")
+	assert.Equal(t, 0, api.Order, "The project should be able to set the order back to 0")
+}
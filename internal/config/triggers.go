@@ -0,0 +1,35 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/amido/mrbuild/internal/match"
+)
+
+// CheckGlobalTriggers ensures that the global trigger patterns are valid and compiles
+// them so that they are only compiled once
+func (ic *InputConfig) CheckGlobalTriggers() error {
+	var err error
+
+	ic.globalTriggers, err = match.CompileExcludes("", ic.GlobalTriggers)
+	if err != nil {
+		return fmt.Errorf("invalid global trigger: %s", err.Error())
+	}
+
+	return nil
+}
+
+// IsGlobalTrigger determines if a change to the file, with a path relative to the root of
+// the repository, affects every project
+// The patterns follow the rules of a .gitignore file, so build/ matches everything in the
+// build directory and Directory.Build.props matches the file in any directory
+func (ic *InputConfig) IsGlobalTrigger(file string) bool {
+
+	if ic.globalTriggers == nil {
+		if err := ic.CheckGlobalTriggers(); err != nil {
+			return false
+		}
+	}
+
+	return file != "" && !ic.globalTriggers.Empty() && ic.globalTriggers.Match(file)
+}
@@ -0,0 +1,183 @@
+package discovery
+
+import (
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/amido/mrbuild/internal/config"
+	"github.com/amido/mrbuild/internal/match"
+	log "github.com/sirupsen/logrus"
+)
+
+// skipDirs are the directories that are never searched for marker files, as they contain
+// the repository, dependencies or tool state rather than projects
+var skipDirs = map[string]bool{
+	".git":         true,
+	".terraform":   true,
+	"node_modules": true,
+}
+
+// Find walks the working directory and creates a project for each folder that contains
+// the marker file of a rule. If a folder contains the markers of more than one rule, the
+// rule that is declared first is used. The projects are sorted by folder
+func Find(conf *config.Config) ([]config.Project, error) {
+
+	var projects []config.Project
+
+	discovery := conf.Input.Discovery
+	if !discovery.Enabled() {
+		return projects, nil
+	}
+
+	exclude, err := match.CompileExcludes("", discovery.Exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	root := conf.Input.Directory.WorkingDir
+
+	// the index of the rule, and the marker, that has been found in each folder
+	type found struct {
+		rule   int
+		marker string
+	}
+	folders := make(map[string]found)
+
+	err = filepath.WalkDir(root, func(full string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			if full != root && skipDirs[entry.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, full)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if !exclude.Empty() && exclude.Match(rel) {
+			return nil
+		}
+
+		folder := path.Dir(rel)
+
+		for i, rule := range discovery.Rules {
+			if !rule.Matches(entry.Name()) {
+				continue
+			}
+
+			if current, ok := folders[folder]; !ok || i < current.rule {
+				folders[folder] = found{rule: i, marker: entry.Name()}
+			}
+			break
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	for folder, item := range folders {
+		project, err := discovery.Rules[item.rule].Project(folder, item.marker)
+		if err != nil {
+			return nil, err
+		}
+
+		projects = append(projects, project)
+	}
+
+	sort.Slice(projects, func(i, j int) bool {
+		return projects[i].Folder < projects[j].Folder
+	})
+
+	return projects, nil
+}
+
+// Merge adds the discovered projects to those that have been configured
+// A project that has been configured overrides a discovered project with the same
+// name or folder
+func Merge(configured []config.Project, discovered []config.Project) []config.Project {
+
+	result := append([]config.Project{}, configured...)
+
+	for _, project := range discovered {
+		if Overridden(configured, project) == "" {
+			result = append(result, project)
+		}
+	}
+
+	return result
+}
+
+// Overridden returns the name of the configured project that overrides the discovered
+// project, or an empty string if it has not been overridden
+func Overridden(configured []config.Project, project config.Project) string {
+
+	for _, item := range configured {
+		if item.Name == project.Name || cleanFolder(item.Folder) == cleanFolder(project.Folder) {
+			return item.Name
+		}
+	}
+
+	return ""
+}
+
+// Apply discovers the projects in the repository and adds them to the configuration
+// It should be called after the configuration has been checked
+func Apply(conf *config.Config, logger *log.Logger) error {
+
+	if !conf.Input.Discovery.Enabled() {
+		return nil
+	}
+
+	discovered, err := Find(conf)
+	if err != nil {
+		return err
+	}
+
+	projects := Merge(conf.Input.Projects, discovered)
+
+	// the defaults are applied to the discovered projects as well
+	for i := len(conf.Input.Projects); i < len(projects); i++ {
+		if projects[i], err = conf.Input.ResolveProject(projects[i]); err != nil {
+			return err
+		}
+
+		if err = projects[i].Check(); err != nil {
+			return err
+		}
+	}
+
+	logger.WithFields(
+		log.Fields{
+			"discovered": len(discovered),
+			"added":      len(projects) - len(conf.Input.Projects),
+		},
+	).Info("Discovered projects")
+
+	conf.Input.Projects = projects
+
+	return nil
+}
+
+// cleanFolder returns the folder in the form used by discovered projects
+func cleanFolder(folder string) string {
+
+	folder = strings.Trim(path.Clean(strings.ReplaceAll(folder, "\\", "/")), "/")
+	if folder == "" {
+		folder = "."
+	}
+
+	return folder
+}
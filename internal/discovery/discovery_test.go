@@ -0,0 +1,89 @@
+package discovery
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/amido/mrbuild/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFile(t *testing.T, root string, path string) {
+	full := filepath.Join(root, filepath.FromSlash(path))
+
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(full, []byte{}, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFind(t *testing.T) {
+	root := t.TempDir()
+
+	writeFile(t, root, "src/api/go.mod")
+	writeFile(t, root, "src/api/Dockerfile")
+	writeFile(t, root, "src/web/package.json")
+	writeFile(t, root, "src/web/node_modules/left-pad/package.json")
+	writeFile(t, root, "src/billing/Billing.csproj")
+	writeFile(t, root, "examples/demo/go.mod")
+
+	conf := &config.Config{}
+	conf.Input.Directory.WorkingDir = root
+	conf.Input.Discovery = config.Discovery{
+		Exclude: []string{"examples/"},
+		Rules: []config.DiscoveryRule{
+			{Marker: "go.mod", Cmd: "go test ./..."},
+			{Marker: "package.json", Name: "web", Cmd: "npm run build"},
+			{Marker: "*.csproj", Cmd: "dotnet build {{ .Marker }}", Order: 2},
+			{Marker: "Dockerfile", Cmd: "docker build -t {{ .Name }} ."},
+		},
+	}
+
+	assert.NoError(t, conf.Input.Discovery.Check())
+
+	projects, err := Find(conf)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 3, len(projects))
+
+	assert.Equal(t, "src/api", projects[0].Name)
+	assert.Equal(t, "go test ./...", projects[0].Build.Cmd, "The first rule that is declared should be used")
+	assert.Equal(t, "src/api/go.mod", projects[0].Discovered)
+	assert.True(t, projects[0].Match("src/api/cmd/main.go"))
+
+	assert.Equal(t, "src/billing", projects[1].Name)
+	assert.Equal(t, "dotnet build Billing.csproj", projects[1].Build.Cmd)
+	assert.Equal(t, 2, projects[1].Order)
+
+	assert.Equal(t, "web", projects[2].Name)
+	assert.Equal(t, "src/web", projects[2].Folder)
+}
+
+func TestMerge(t *testing.T) {
+
+	configured := []config.Project{
+		{Name: "api", Folder: "src/api/"},
+		{Name: "web", Folder: "frontend"},
+	}
+
+	discovered := []config.Project{
+		{Name: "src/api", Folder: "src/api"},
+		{Name: "web", Folder: "src/web"},
+		{Name: "src/billing", Folder: "src/billing"},
+	}
+
+	projects := Merge(configured, discovered)
+
+	names := []string{}
+	for _, project := range projects {
+		names = append(names, project.Name)
+	}
+
+	assert.Equal(t, []string{"api", "web", "src/billing"}, names, "Configured projects should override those with the same name or folder")
+	assert.Equal(t, "api", Overridden(configured, discovered[0]))
+	assert.Equal(t, "", Overridden(configured, discovered[2]))
+}
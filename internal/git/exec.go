@@ -0,0 +1,301 @@
+package git
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/amido/mrbuild/internal/config"
+	"github.com/amido/mrbuild/internal/models"
+	"github.com/amido/mrbuild/internal/parser"
+	"github.com/amido/mrbuild/internal/util"
+	"github.com/sirupsen/logrus"
+)
+
+// ExecProvider reads the changes in the repository by running the git executable
+type ExecProvider struct {
+	Config *config.Config
+	Logger *logrus.Logger
+	Dir    string // Directory that git is run in
+}
+
+// NewExecProvider allocates a new ExecProvider that runs git in the working directory
+func NewExecProvider(conf *config.Config, logger *logrus.Logger) *ExecProvider {
+	return &ExecProvider{
+		Config: conf,
+		Logger: logger,
+		Dir:    conf.Input.Directory.WorkingDir,
+	}
+}
+
+// ResolveRef returns the SHA of the commit that the ref points to
+func (p *ExecProvider) ResolveRef(ref string) (string, error) {
+	return p.run("rev-parse", "--verify", "--quiet", ref+"^{commit}")
+}
+
+// CommitMessage returns the full message of the commit that the ref points to
+func (p *ExecProvider) CommitMessage(ref string) (string, error) {
+	return p.run("log", "-1", "--format=%B", ref)
+}
+
+// MergeBase returns the SHA of the best common ancestor of HEAD and the ref
+func (p *ExecProvider) MergeBase(ref string) (string, error) {
+	return p.run("merge-base", "HEAD", ref)
+}
+
+// DiffCommits returns the files that have changed between the two commits
+func (p *ExecProvider) DiffCommits(from string, to string) ([]models.ChangedFile, error) {
+	return p.diff(from, to)
+}
+
+// DiffWorktree returns the files that have changed between the commit and the working
+// tree, or the index if cached is set
+func (p *ExecProvider) DiffWorktree(from string, cached bool) ([]models.ChangedFile, error) {
+	if cached {
+		return p.diff("--cached", from)
+	}
+
+	return p.diff(from)
+}
+
+// Unstaged returns the files in the working tree that have changes which have not been staged
+func (p *ExecProvider) Unstaged() ([]models.ChangedFile, error) {
+	return p.diff()
+}
+
+// Untracked returns the files that are not tracked by git and are not ignored
+func (p *ExecProvider) Untracked() ([]string, error) {
+
+	var files []string
+
+	output, err := p.run("ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) != "" {
+			files = append(files, strings.TrimSpace(line))
+		}
+	}
+
+	return files, nil
+}
+
+// Submodules returns the paths of the submodules in the repository
+func (p *ExecProvider) Submodules() ([]string, error) {
+
+	var paths []string
+
+	output, err := p.run("submodule", "status")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+
+		// each line is the status, sha, path and optionally the description of the commit
+		fields := strings.Fields(strings.TrimLeft(line, " +-U"))
+		if len(fields) > 1 {
+			paths = append(paths, fields[1])
+		}
+	}
+
+	return paths, nil
+}
+
+// SubmoduleCommit returns the commit that the submodule at the path points to in the
+// ref, or the commit that is checked out in the submodule if the ref is empty
+func (p *ExecProvider) SubmoduleCommit(ref string, path string) (string, error) {
+
+	if ref == "" {
+		output, err := p.run("submodule", "status", "--", path)
+		if err != nil {
+			return "", err
+		}
+
+		if strings.HasPrefix(output, "-") {
+			return "", fmt.Errorf("submodule has not been initialised: %s", path)
+		}
+
+		fields := strings.Fields(strings.TrimLeft(output, " +U"))
+		if len(fields) == 0 {
+			return "", nil
+		}
+
+		return fields[0], nil
+	}
+
+	// the output is the mode, type, sha and path of the entry in the tree
+	output, err := p.run("ls-tree", ref, "--", path)
+	if err != nil {
+		return "", err
+	}
+
+	fields := strings.Fields(output)
+	if len(fields) < 3 || fields[1] != "commit" {
+		return "", nil
+	}
+
+	return fields[2], nil
+}
+
+// Submodule returns a provider that runs git in the directory of the submodule
+func (p *ExecProvider) Submodule(path string) (Provider, error) {
+
+	dir := filepath.Join(p.Dir, filepath.FromSlash(path))
+	if !util.Exists(filepath.Join(dir, ".git")) {
+		return nil, fmt.Errorf("submodule has not been initialised: %s", path)
+	}
+
+	return &ExecProvider{
+		Config: p.Config,
+		Logger: p.Logger,
+		Dir:    dir,
+	}, nil
+}
+
+// ChangedLines returns the lines that have been added or removed in the file, ignoring
+// changes to whitespace and blank lines
+func (p *ExecProvider) ChangedLines(from string, to string, cached bool, path string) ([]string, error) {
+
+	var lines []string
+
+	arguments := []string{"diff", "--no-color", "--no-ext-diff", "-w", "--ignore-blank-lines", "-U0"}
+	if to != "" {
+		arguments = append(arguments, from, to)
+	} else if cached {
+		arguments = append(arguments, "--cached", from)
+	} else {
+		arguments = append(arguments, from)
+	}
+
+	output, err := p.run(append(arguments, "--", path)...)
+	if err != nil {
+		return nil, err
+	}
+
+	// only the lines within a hunk are changes, the lines before the first hunk
+	// are the header of the diff
+	inHunk := false
+	for _, line := range strings.Split(output, "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff "):
+			inHunk = false
+		case strings.HasPrefix(line, "@@"):
+			inHunk = true
+		case inHunk && (strings.HasPrefix(line, "+") || strings.HasPrefix(line, "-")):
+			lines = append(lines, line[1:])
+		}
+	}
+
+	return lines, nil
+}
+
+// Tag returns the SHA of the commit that the tag points to, or an empty string if the
+// tag does not exist. For an annotated tag the commit that it points to is returned
+func (p *ExecProvider) Tag(name string) (string, error) {
+
+	output, err := p.run("for-each-ref", "--format=%(objectname) %(*objectname)", "refs/tags/"+name)
+	if err != nil {
+		return "", err
+	}
+
+	fields := strings.Fields(output)
+	if len(fields) == 0 {
+		return "", nil
+	}
+
+	return fields[len(fields)-1], nil
+}
+
+// SetTag creates the lightweight tag, or moves it if it already exists
+func (p *ExecProvider) SetTag(name string, ref string) error {
+
+	_, err := p.run("tag", "-f", name, ref)
+
+	return err
+}
+
+// Notes returns the notes in the notes ref that are attached to the commits in the
+// history of HEAD, with the most recent commit first
+func (p *ExecProvider) Notes(notesRef string) ([]Note, error) {
+
+	var notes []Note
+
+	// git warns if the notes ref does not exist, so check for it first
+	exists, err := p.run("for-each-ref", "refs/notes/"+notesRef)
+	if err != nil || exists == "" {
+		return nil, err
+	}
+
+	// each commit is separated by a record separator and the SHA and the note by a unit separator
+	output, err := p.run("log", "--notes="+notesRef, "--format=%H%x1f%N%x1e", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, record := range strings.Split(output, "\x1e") {
+		parts := strings.SplitN(strings.TrimSpace(record), "\x1f", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[1]) == "" {
+			continue
+		}
+
+		notes = append(notes, newNote(parts[0], parts[1]))
+	}
+
+	return notes, nil
+}
+
+// AppendNote appends the line to the note that is attached to the commit in the notes ref
+func (p *ExecProvider) AppendNote(notesRef string, ref string, line string) error {
+
+	_, err := p.run("notes", "--ref="+notesRef, "append", "-m", line, ref)
+
+	return err
+}
+
+// IsShallow states if the repository is a shallow clone
+func (p *ExecProvider) IsShallow() (bool, error) {
+
+	output, err := p.run("rev-parse", "--is-shallow-repository")
+	if err != nil {
+		return false, err
+	}
+
+	return output == "true", nil
+}
+
+// Deepen fetches the specified number of additional commits into a shallow clone
+func (p *ExecProvider) Deepen(depth int) error {
+
+	_, err := p.run("fetch", "--quiet", fmt.Sprintf("--deepen=%d", depth))
+
+	return err
+}
+
+// diff runs the git diff command, with rename detection, and parses the output
+func (p *ExecProvider) diff(arguments ...string) ([]models.ChangedFile, error) {
+
+	output, err := p.run(append([]string{"diff", "--name-status", "-M"}, arguments...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return parser.ParseNameStatus(output), nil
+}
+
+// run runs the git command with the specified arguments in the working directory
+// The arguments are passed to git as they are, rather than through a shell, so that refs
+// such as HEAD^2 and paths with spaces are not split or escaped
+// As git is only used to read the repository the command is run even in DryRun mode
+func (p *ExecProvider) run(arguments ...string) (string, error) {
+	return p.Config.ExecuteArgs(
+		p.Dir,
+		p.Logger,
+		"git",
+		append([]string{"--no-pager"}, arguments...),
+		false,
+		true,
+	)
+}
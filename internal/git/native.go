@@ -0,0 +1,879 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/amido/mrbuild/internal/models"
+	gogit "github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/gitattributes"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/utils/diff"
+	"github.com/sergi/go-diff/diffmatchpatch"
+	"github.com/sirupsen/logrus"
+)
+
+// lookup returns the hash of the object at the path, and if the path exists,
+// on one side of a comparison
+type lookup func(path string) (plumbing.Hash, bool, error)
+
+// NativeProvider reads the changes from the repository in-process, so that the
+// git executable does not need to be installed
+type NativeProvider struct {
+	Logger *logrus.Logger
+
+	repo *gogit.Repository
+	root string
+}
+
+// NewNativeProvider opens the repository that contains the specified directory
+func NewNativeProvider(dir string, logger *logrus.Logger) (*NativeProvider, error) {
+
+	repo, err := gogit.PlainOpenWithOptions(dir, &gogit.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, fmt.Errorf("unable to open git repository in '%s': %s", dir, err.Error())
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, err
+	}
+
+	return &NativeProvider{
+		Logger: logger,
+		repo:   repo,
+		root:   worktree.Filesystem.Root(),
+	}, nil
+}
+
+// ResolveRef returns the SHA of the commit that the ref points to
+func (p *NativeProvider) ResolveRef(ref string) (string, error) {
+
+	commit, err := p.commit(ref)
+	if err != nil {
+		return "", err
+	}
+
+	return commit.Hash.String(), nil
+}
+
+// CommitMessage returns the full message of the commit that the ref points to
+func (p *NativeProvider) CommitMessage(ref string) (string, error) {
+
+	commit, err := p.commit(ref)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(commit.Message), nil
+}
+
+// MergeBase returns the SHA of the best common ancestor of HEAD and the ref
+func (p *NativeProvider) MergeBase(ref string) (string, error) {
+
+	head, err := p.commit("HEAD")
+	if err != nil {
+		return "", err
+	}
+
+	other, err := p.commit(ref)
+	if err != nil {
+		return "", err
+	}
+
+	bases, err := head.MergeBase(other)
+	if err != nil {
+		return "", err
+	}
+
+	if len(bases) == 0 {
+		return "", fmt.Errorf("no merge-base found for HEAD and %s", ref)
+	}
+
+	return bases[0].Hash.String(), nil
+}
+
+// DiffCommits returns the files that have changed between the two commits
+func (p *NativeProvider) DiffCommits(from string, to string) ([]models.ChangedFile, error) {
+
+	fromTree, err := p.tree(from)
+	if err != nil {
+		return nil, err
+	}
+
+	toTree, err := p.tree(to)
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := diffTreePaths(fromTree, toTree)
+	if err != nil {
+		return nil, err
+	}
+
+	return compare(paths, treeLookup(fromTree), treeLookup(toTree))
+}
+
+// DiffWorktree returns the files that have changed between the commit and the working
+// tree, or the index if cached is set
+// The files that may have changed are those that differ between the commit and HEAD and
+// those that git reports as having changed since HEAD
+func (p *NativeProvider) DiffWorktree(from string, cached bool) ([]models.ChangedFile, error) {
+
+	fromTree, err := p.tree(from)
+	if err != nil {
+		return nil, err
+	}
+
+	headTree, err := p.tree("HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := diffTreePaths(fromTree, headTree)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := p.status()
+	if err != nil {
+		return nil, err
+	}
+
+	for path, file := range status {
+		if file.Worktree == gogit.Untracked {
+			continue
+		}
+
+		if file.Staging != gogit.Unmodified || (!cached && file.Worktree != gogit.Unmodified) {
+			paths = append(paths, path)
+		}
+	}
+
+	idx, err := p.repo.Storer.Index()
+	if err != nil {
+		return nil, err
+	}
+
+	to := p.worktreeLookup(idx)
+	if cached {
+		to = indexLookup(idx)
+	}
+
+	return compare(paths, treeLookup(fromTree), to)
+}
+
+// Unstaged returns the files in the working tree that have changes which have not been staged
+func (p *NativeProvider) Unstaged() ([]models.ChangedFile, error) {
+
+	var paths []string
+
+	status, err := p.status()
+	if err != nil {
+		return nil, err
+	}
+
+	for path, file := range status {
+		if file.Worktree != gogit.Unmodified && file.Worktree != gogit.Untracked {
+			paths = append(paths, path)
+		}
+	}
+
+	idx, err := p.repo.Storer.Index()
+	if err != nil {
+		return nil, err
+	}
+
+	return compare(paths, indexLookup(idx), p.worktreeLookup(idx))
+}
+
+// Untracked returns the files that are not tracked by git and are not ignored
+func (p *NativeProvider) Untracked() ([]string, error) {
+
+	var files []string
+
+	status, err := p.status()
+	if err != nil {
+		return nil, err
+	}
+
+	for path, file := range status {
+		if file.Worktree == gogit.Untracked {
+			files = append(files, path)
+		}
+	}
+
+	sort.Strings(files)
+
+	return files, nil
+}
+
+// Submodules returns the paths of the submodules in the repository
+func (p *NativeProvider) Submodules() ([]string, error) {
+
+	var paths []string
+
+	worktree, err := p.repo.Worktree()
+	if err != nil {
+		return nil, err
+	}
+
+	submodules, err := worktree.Submodules()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, submodule := range submodules {
+		paths = append(paths, submodule.Config().Path)
+	}
+
+	return paths, nil
+}
+
+// SubmoduleCommit returns the commit that the submodule at the path points to in the
+// ref, or the commit that is checked out in the submodule if the ref is empty
+func (p *NativeProvider) SubmoduleCommit(ref string, path string) (string, error) {
+
+	if ref == "" {
+		submodule, err := p.Submodule(path)
+		if err != nil {
+			return "", err
+		}
+
+		return submodule.ResolveRef("HEAD")
+	}
+
+	tree, err := p.tree(ref)
+	if err != nil {
+		return "", err
+	}
+
+	entry, err := tree.FindEntry(path)
+	if err != nil || entry.Mode != filemode.Submodule {
+		return "", nil
+	}
+
+	return entry.Hash.String(), nil
+}
+
+// Submodule returns a provider that reads the repository of the submodule
+func (p *NativeProvider) Submodule(path string) (Provider, error) {
+
+	dir := filepath.Join(p.root, filepath.FromSlash(path))
+
+	repo, err := gogit.PlainOpen(dir)
+	if err != nil {
+		return nil, fmt.Errorf("submodule has not been initialised: %s", path)
+	}
+
+	return &NativeProvider{
+		Logger: p.Logger,
+		repo:   repo,
+		root:   dir,
+	}, nil
+}
+
+// ChangedLines returns the lines that have been added or removed in the file, ignoring
+// changes to whitespace and blank lines
+func (p *NativeProvider) ChangedLines(from string, to string, cached bool, path string) ([]string, error) {
+
+	fromTree, err := p.tree(from)
+	if err != nil {
+		return nil, err
+	}
+
+	before, err := blobContent(p.repo, treeLookup(fromTree), path)
+	if err != nil {
+		return nil, err
+	}
+
+	var after []byte
+
+	switch {
+	case to != "":
+		toTree, err := p.tree(to)
+		if err != nil {
+			return nil, err
+		}
+
+		after, err = blobContent(p.repo, treeLookup(toTree), path)
+		if err != nil {
+			return nil, err
+		}
+
+	case cached:
+		idx, err := p.repo.Storer.Index()
+		if err != nil {
+			return nil, err
+		}
+
+		after, err = blobContent(p.repo, indexLookup(idx), path)
+		if err != nil {
+			return nil, err
+		}
+
+	default:
+		after, err = os.ReadFile(filepath.Join(p.root, filepath.FromSlash(path)))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	return changedLines(string(before), string(after)), nil
+}
+
+// Tag returns the SHA of the commit that the tag points to, or an empty string if the
+// tag does not exist. For an annotated tag the commit that it points to is returned
+func (p *NativeProvider) Tag(name string) (string, error) {
+
+	ref, err := p.repo.Tag(name)
+	if errors.Is(err, gogit.ErrTagNotFound) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	tag, err := p.repo.TagObject(ref.Hash())
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		return ref.Hash().String(), nil
+	} else if err != nil {
+		return "", err
+	}
+
+	commit, err := tag.Commit()
+	if err != nil {
+		return "", err
+	}
+
+	return commit.Hash.String(), nil
+}
+
+// SetTag creates the lightweight tag, or moves it if it already exists
+func (p *NativeProvider) SetTag(name string, ref string) error {
+
+	commit, err := p.commit(ref)
+	if err != nil {
+		return err
+	}
+
+	err = p.repo.DeleteTag(name)
+	if err != nil && !errors.Is(err, gogit.ErrTagNotFound) {
+		return err
+	}
+
+	_, err = p.repo.CreateTag(name, commit.Hash, nil)
+
+	return err
+}
+
+// Notes returns the notes in the notes ref that are attached to the commits in the
+// history of HEAD, with the most recent commit first
+func (p *NativeProvider) Notes(notesRef string) ([]Note, error) {
+
+	var notes []Note
+
+	ref, err := p.repo.Reference(plumbing.ReferenceName("refs/notes/"+notesRef), true)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	tree, err := p.tree(ref.Hash().String())
+	if err != nil {
+		return nil, err
+	}
+
+	// the notes are files named after the commit, which may be split into directories
+	content := make(map[string]string)
+	err = tree.Files().ForEach(func(file *object.File) error {
+		text, err := file.Contents()
+		content[strings.ReplaceAll(file.Name, "/", "")] = text
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := p.commit("HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	commits, err := p.repo.Log(&gogit.LogOptions{From: head.Hash, Order: gogit.LogOrderCommitterTime})
+	if err != nil {
+		return nil, err
+	}
+
+	err = commits.ForEach(func(commit *object.Commit) error {
+		if text, ok := content[commit.Hash.String()]; ok {
+			notes = append(notes, newNote(commit.Hash.String(), text))
+		}
+
+		return nil
+	})
+
+	return notes, err
+}
+
+// AppendNote is not supported by the native provider
+func (p *NativeProvider) AppendNote(notesRef string, ref string, line string) error {
+	return fmt.Errorf("writing git notes is not supported by the native git provider")
+}
+
+// IsShallow states if the repository is a shallow clone
+func (p *NativeProvider) IsShallow() (bool, error) {
+
+	commits, err := p.repo.Storer.Shallow()
+	if err != nil {
+		return false, err
+	}
+
+	return len(commits) > 0, nil
+}
+
+// Deepen is not supported by the native provider as it does not fetch from remotes
+func (p *NativeProvider) Deepen(depth int) error {
+	return fmt.Errorf("deepening a shallow clone is not supported by the native git provider")
+}
+
+// commit returns the commit that the ref points to
+func (p *NativeProvider) commit(ref string) (*object.Commit, error) {
+
+	hash, err := p.repo.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve ref '%s': %s", ref, err.Error())
+	}
+
+	return p.repo.CommitObject(*hash)
+}
+
+// tree returns the tree of the commit that the ref points to
+func (p *NativeProvider) tree(ref string) (*object.Tree, error) {
+
+	commit, err := p.commit(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	return commit.Tree()
+}
+
+// status returns the status of the working tree
+func (p *NativeProvider) status() (gogit.Status, error) {
+
+	worktree, err := p.repo.Worktree()
+	if err != nil {
+		return nil, err
+	}
+
+	return worktree.Status()
+}
+
+// worktreeLookup returns a lookup that hashes the files in the working tree
+// Submodules are directories in the working tree, so the commit recorded in the index is used
+// The line endings of each file are converted in the same way as git does when the file is
+// added, so a file that has been checked out with CRLF line endings is not reported as changed
+func (p *NativeProvider) worktreeLookup(idx *index.Index) lookup {
+
+	filter := &cleanFilter{provider: p, idx: idx}
+
+	return func(path string) (plumbing.Hash, bool, error) {
+
+		full := filepath.Join(p.root, filepath.FromSlash(path))
+
+		info, err := os.Lstat(full)
+		if errors.Is(err, os.ErrNotExist) {
+			return plumbing.ZeroHash, false, nil
+		} else if err != nil {
+			return plumbing.ZeroHash, false, err
+		}
+
+		var content []byte
+
+		switch {
+		case info.IsDir():
+			return indexLookup(idx)(path)
+
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(full)
+			if err != nil {
+				return plumbing.ZeroHash, false, err
+			}
+
+			content = []byte(filepath.ToSlash(target))
+
+		default:
+			content, err = os.ReadFile(full)
+			if err != nil {
+				return plumbing.ZeroHash, false, err
+			}
+
+			content, err = filter.clean(path, content)
+			if err != nil {
+				return plumbing.ZeroHash, false, err
+			}
+		}
+
+		return plumbing.ComputeHash(plumbing.BlobObject, content), true, nil
+	}
+}
+
+// cleanFilter converts the line endings of the files in the working tree to LF when git
+// would, according to the text and eol attributes of the file and the core.autocrlf setting
+// The attributes and the setting are only read once a file with CRLF line endings is found
+type cleanFilter struct {
+	provider *NativeProvider
+	idx      *index.Index
+
+	loaded     bool
+	autoCRLF   bool
+	attributes []gitattributes.MatchAttribute
+}
+
+// clean returns the content of the file as it would be added to the index
+func (f *cleanFilter) clean(path string, content []byte) ([]byte, error) {
+
+	if !bytes.Contains(content, []byte("\r\n")) {
+		return content, nil
+	}
+
+	if err := f.load(); err != nil {
+		return nil, err
+	}
+
+	text, auto := f.textAttribute(path)
+	if !text {
+		return content, nil
+	}
+
+	// when git determines if a file is text it does not convert a binary file, or a file
+	// that has already been committed with CRLF line endings
+	if auto {
+		if isBinary(content) {
+			return content, nil
+		}
+
+		committed, err := blobContent(f.provider.repo, indexLookup(f.idx), path)
+		if err != nil || bytes.Contains(committed, []byte("\r\n")) {
+			return content, err
+		}
+	}
+
+	return bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n")), nil
+}
+
+// textAttribute states if the line endings of the file should be converted, and if git
+// should determine if the file is text from its content
+func (f *cleanFilter) textAttribute(path string) (bool, bool) {
+
+	parts := strings.Split(path, "/")
+	found := make(map[string]gitattributes.Attribute)
+
+	// the patterns are in ascending order of priority, so the last pattern that sets
+	// each attribute is used
+	for i := len(f.attributes) - 1; i >= 0; i-- {
+		if f.attributes[i].Pattern == nil || !f.attributes[i].Pattern.Match(parts) {
+			continue
+		}
+
+		for _, attribute := range f.attributes[i].Attributes {
+			if _, ok := found[attribute.Name()]; !ok {
+				found[attribute.Name()] = attribute
+			}
+		}
+	}
+
+	if binary, ok := found["binary"]; ok && binary.IsSet() {
+		return false, false
+	}
+
+	if text, ok := found["text"]; ok {
+		switch {
+		case text.IsSet():
+			return true, false
+		case text.IsUnset():
+			return false, false
+		case text.IsValueSet() && text.Value() == "auto":
+			return true, true
+		}
+	}
+
+	// setting the eol attribute marks the file as text
+	if eol, ok := found["eol"]; ok && eol.IsValueSet() {
+		return true, false
+	}
+
+	return f.autoCRLF, true
+}
+
+// load reads the core.autocrlf setting and the attributes of the repository, from the
+// .gitattributes files in the working tree and .git/info/attributes
+func (f *cleanFilter) load() error {
+
+	if f.loaded {
+		return nil
+	}
+	f.loaded = true
+
+	conf, err := f.provider.repo.ConfigScoped(gitconfig.SystemScope)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(conf.Raw.Section("core").Option("autocrlf")) {
+	case "true", "input":
+		f.autoCRLF = true
+	}
+
+	worktree, err := f.provider.repo.Worktree()
+	if err != nil {
+		return err
+	}
+
+	f.attributes, err = gitattributes.ReadPatterns(worktree.Filesystem, nil)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(filepath.Join(f.provider.root, ".git", "info", "attributes"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := gitattributes.ReadAttributes(file, nil, true)
+	f.attributes = append(f.attributes, info...)
+
+	return err
+}
+
+// isBinary states if git would treat the content as binary when converting the line
+// endings, which is when it has a NUL byte or a CR that is not followed by a LF
+func isBinary(content []byte) bool {
+
+	for i, b := range content {
+		if b == 0 || (b == '\r' && (i+1 == len(content) || content[i+1] != '\n')) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// indexLookup returns a lookup that finds the files in the index
+func indexLookup(idx *index.Index) lookup {
+	return func(path string) (plumbing.Hash, bool, error) {
+
+		entry, err := idx.Entry(path)
+		if errors.Is(err, index.ErrEntryNotFound) {
+			return plumbing.ZeroHash, false, nil
+		} else if err != nil {
+			return plumbing.ZeroHash, false, err
+		}
+
+		return entry.Hash, true, nil
+	}
+}
+
+// treeLookup returns a lookup that finds the files in the tree of a commit
+func treeLookup(tree *object.Tree) lookup {
+	return func(path string) (plumbing.Hash, bool, error) {
+
+		entry, err := tree.FindEntry(path)
+		if errors.Is(err, object.ErrEntryNotFound) || errors.Is(err, object.ErrDirectoryNotFound) || errors.Is(err, plumbing.ErrObjectNotFound) {
+			return plumbing.ZeroHash, false, nil
+		} else if err != nil {
+			return plumbing.ZeroHash, false, err
+		}
+
+		if entry.Mode == filemode.Dir {
+			return plumbing.ZeroHash, false, nil
+		}
+
+		return entry.Hash, true, nil
+	}
+}
+
+// blobContent returns the content of the file at the path, or nil if it does not exist
+func blobContent(repo *gogit.Repository, find lookup, path string) ([]byte, error) {
+
+	hash, ok, err := find(path)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	blob, err := repo.BlobObject(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := blob.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return io.ReadAll(reader)
+}
+
+// changedLines compares the content line by line, in the same way as git diff -w
+// --ignore-blank-lines. The whitespace is removed from each line before they are compared
+// and blank lines are not included, the original lines that differ are returned
+func changedLines(before string, after string) []string {
+
+	var lines []string
+
+	oldLines, oldKeys := significantLines(before)
+	newLines, newKeys := significantLines(after)
+
+	i, j := 0, 0
+	for _, d := range diff.Do(oldKeys, newKeys) {
+		count := strings.Count(d.Text, "\n")
+
+		switch d.Type {
+		case diffmatchpatch.DiffEqual:
+			i += count
+			j += count
+		case diffmatchpatch.DiffDelete:
+			lines = append(lines, oldLines[i:i+count]...)
+			i += count
+		case diffmatchpatch.DiffInsert:
+			lines = append(lines, newLines[j:j+count]...)
+			j += count
+		}
+	}
+
+	return lines
+}
+
+// significantLines returns the lines that are not blank, and the lines without any
+// whitespace joined together so that they can be compared
+func significantLines(content string) ([]string, string) {
+
+	var lines []string
+	var keys strings.Builder
+
+	for _, line := range strings.Split(content, "\n") {
+		key := strings.Join(strings.Fields(line), "")
+		if key == "" {
+			continue
+		}
+
+		lines = append(lines, strings.TrimRight(line, "\r"))
+		keys.WriteString(key)
+		keys.WriteString("\n")
+	}
+
+	return lines, keys.String()
+}
+
+// diffTreePaths returns the paths of the files that are different in the two trees
+func diffTreePaths(from *object.Tree, to *object.Tree) ([]string, error) {
+
+	var paths []string
+
+	changes, err := object.DiffTree(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, change := range changes {
+		if change.From.Name != "" {
+			paths = append(paths, change.From.Name)
+		}
+
+		if change.To.Name != "" && change.To.Name != change.From.Name {
+			paths = append(paths, change.To.Name)
+		}
+	}
+
+	return paths, nil
+}
+
+// compare determines the status of each of the paths by comparing the hash of the file on
+// each side. Files that have been deleted and added with the same content are treated as
+// a rename, as git does for an exact rename
+// Renames where the content has also changed are not detected, unlike git diff -M, so they
+// are reported as the old file being deleted and the new file being added. This affects the
+// same projects, as a rename is treated as a change to both the old and new path
+func compare(paths []string, from lookup, to lookup) ([]models.ChangedFile, error) {
+
+	var files []models.ChangedFile
+
+	seen := make(map[string]bool)
+	deleted := make(map[plumbing.Hash]int)
+	hashes := make(map[string]plumbing.Hash)
+
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+
+		fromHash, inFrom, err := from(path)
+		if err != nil {
+			return nil, err
+		}
+
+		toHash, inTo, err := to(path)
+		if err != nil {
+			return nil, err
+		}
+
+		file := models.ChangedFile{Path: path}
+
+		switch {
+		case inFrom && inTo && fromHash != toHash:
+			file.Status = models.ChangeModified
+		case inFrom && !inTo:
+			file.Status = models.ChangeDeleted
+			deleted[fromHash] = len(files)
+		case !inFrom && inTo:
+			file.Status = models.ChangeAdded
+			hashes[path] = toHash
+		default:
+			continue
+		}
+
+		files = append(files, file)
+	}
+
+	// pair up the added files with deleted files that have the same content
+	removed := make(map[int]bool)
+	for i := range files {
+		if files[i].Status != models.ChangeAdded {
+			continue
+		}
+
+		if pos, ok := deleted[hashes[files[i].Path]]; ok && !removed[pos] {
+			files[i].Status = models.ChangeRenamed
+			files[i].OldPath = files[pos].Path
+			removed[pos] = true
+		}
+	}
+
+	var result []models.ChangedFile
+	for i, file := range files {
+		if !removed[i] {
+			result = append(result, file)
+		}
+	}
+
+	return result, nil
+}
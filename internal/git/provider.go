@@ -0,0 +1,115 @@
+package git
+
+import (
+	"strings"
+
+	"github.com/amido/mrbuild/internal/config"
+	"github.com/amido/mrbuild/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// Provider is the interface that is used to read the changes from a git repository
+// This allows the git executable to be used or for the repository to be read natively
+type Provider interface {
+
+	// ResolveRef returns the SHA of the commit that the ref points to
+	ResolveRef(ref string) (string, error)
+
+	// CommitMessage returns the full message of the commit that the ref points to
+	CommitMessage(ref string) (string, error)
+
+	// MergeBase returns the SHA of the best common ancestor of HEAD and the ref
+	MergeBase(ref string) (string, error)
+
+	// DiffCommits returns the files that have changed between the two commits
+	DiffCommits(from string, to string) ([]models.ChangedFile, error)
+
+	// DiffWorktree returns the files that have changed between the commit and the working
+	// tree. If cached is set then the index is used instead of the working tree
+	DiffWorktree(from string, cached bool) ([]models.ChangedFile, error)
+
+	// Unstaged returns the files in the working tree that have changes which have not been staged
+	Unstaged() ([]models.ChangedFile, error)
+
+	// Untracked returns the files that are not tracked by git and are not ignored
+	Untracked() ([]string, error)
+
+	// Submodules returns the paths of the submodules in the repository
+	Submodules() ([]string, error)
+
+	// SubmoduleCommit returns the commit that the submodule at the path points to in the
+	// ref. If the ref is empty the commit checked out in the submodule is returned. An empty
+	// string is returned if the submodule does not exist in the ref
+	SubmoduleCommit(ref string, path string) (string, error)
+
+	// Submodule returns a provider that reads the repository of the submodule at the path
+	Submodule(path string) (Provider, error)
+
+	// ChangedLines returns the lines that have been added or removed in the file between the
+	// two commits, ignoring changes to whitespace and blank lines. If to is empty the working
+	// tree is used, or the index if cached is set
+	ChangedLines(from string, to string, cached bool, path string) ([]string, error)
+
+	// Tag returns the SHA of the commit that the tag points to, or an empty string if the
+	// tag does not exist
+	Tag(name string) (string, error)
+
+	// SetTag creates the lightweight tag, or moves it if it already exists, so that it
+	// points to the ref
+	SetTag(name string, ref string) error
+
+	// Notes returns the notes in the notes ref that are attached to the commits in the
+	// history of HEAD, with the most recent commit first
+	Notes(notesRef string) ([]Note, error)
+
+	// AppendNote appends the line to the note that is attached to the commit in the notes ref
+	AppendNote(notesRef string, ref string, line string) error
+
+	// IsShallow states if the repository is a shallow clone
+	IsShallow() (bool, error)
+
+	// Deepen fetches the specified number of additional commits into a shallow clone
+	Deepen(depth int) error
+}
+
+// Note is a git note that is attached to a commit
+type Note struct {
+	Commit string   // SHA of the commit that the note is attached to
+	Lines  []string // Lines of the note that are not blank
+}
+
+// NewProvider returns the git provider that has been set in the configuration
+func NewProvider(conf *config.Config, logger *logrus.Logger) (Provider, error) {
+
+	logger.WithFields(
+		logrus.Fields{
+			"provider": conf.Input.Compare.Provider,
+		},
+	).Debug("Configuring git provider")
+
+	if conf.Input.Compare.Provider == config.GitProviderNative {
+		provider, err := NewNativeProvider(conf.Input.Directory.WorkingDir, logger)
+		if err != nil {
+			// return a nil interface, rather than an interface holding a nil provider
+			return nil, err
+		}
+
+		return provider, nil
+	}
+
+	return NewExecProvider(conf, logger), nil
+}
+
+// newNote creates a note for the commit from the content, ignoring any blank lines
+func newNote(commit string, content string) Note {
+
+	note := Note{Commit: commit}
+
+	for _, line := range strings.Split(content, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			note.Lines = append(note.Lines, line)
+		}
+	}
+
+	return note
+}
@@ -0,0 +1,317 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/amido/mrbuild/internal/config"
+	"github.com/amido/mrbuild/internal/models"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// testRepo is a temporary git repository that tests can make changes in
+type testRepo struct {
+	t   *testing.T
+	Dir string
+}
+
+// newTestRepo creates a git repository in a temporary directory with an
+// initial commit on the main branch
+func newTestRepo(t *testing.T) *testRepo {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+
+	repo := &testRepo{t: t, Dir: t.TempDir()}
+	repo.Git("init", "-q", "-b", "main")
+	repo.Git("config", "core.autocrlf", "false")
+	repo.Write("README.md", "readme\n")
+	repo.Write("src/api/main.go", "package main\n\nfunc main() {\n}\n")
+	repo.Write("src/web/index.js", "console.log('web')\n")
+	repo.Commit("initial commit")
+
+	return repo
+}
+
+// Git runs the git command in the repository and returns the output
+func (r *testRepo) Git(args ...string) string {
+	args = append([]string{"-c", "user.name=mrbuild", "-c", "user.email=mrbuild@example.com", "-c", "commit.gpgsign=false"}, args...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.Dir
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		r.t.Fatalf("git %s failed: %s\n%s", strings.Join(args, " "), err.Error(), out)
+	}
+
+	return strings.TrimSpace(string(out))
+}
+
+// Write creates or overwrites the file in the repository
+func (r *testRepo) Write(path string, content string) {
+	full := filepath.Join(r.Dir, path)
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		r.t.Fatal(err)
+	}
+
+	if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+// Commit stages all the changes and commits them
+func (r *testRepo) Commit(message string) string {
+	r.Git("add", "-A")
+	r.Git("commit", "-q", "-m", message)
+
+	return r.Git("rev-parse", "HEAD")
+}
+
+// providers returns the exec and native providers for the repository
+func (r *testRepo) providers() map[string]Provider {
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+
+	conf := &config.Config{}
+	conf.Input.Directory.WorkingDir = r.Dir
+
+	native, err := NewNativeProvider(r.Dir, logger)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+
+	return map[string]Provider{
+		config.GitProviderExec:   NewExecProvider(conf, logger),
+		config.GitProviderNative: native,
+	}
+}
+
+// sortFiles sorts the changed files by their path so that the providers can be compared
+func sortFiles(files []models.ChangedFile) []models.ChangedFile {
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+
+	return files
+}
+
+// TestProvidersMatch tests that the native provider reads the same changes as git
+func TestProvidersMatch(t *testing.T) {
+
+	tables := []struct {
+		name     string
+		setup    func(repo *testRepo) string
+		read     func(provider Provider, base string) (interface{}, error)
+		expected interface{}
+	}{
+		{
+			"modified, added and deleted",
+			func(repo *testRepo) string {
+				base := repo.Git("rev-parse", "HEAD")
+				repo.Write("src/api/main.go", "package main\n\nfunc main() {\n\tprintln()\n}\n")
+				repo.Write("src/api/handler.go", "package main\n")
+				assert.NoError(t, os.Remove(filepath.Join(repo.Dir, "src/web/index.js")))
+				repo.Commit("change")
+
+				return base
+			},
+			func(provider Provider, base string) (interface{}, error) {
+				files, err := provider.DiffCommits(base, "HEAD")
+				return sortFiles(files), err
+			},
+			[]models.ChangedFile{
+				{Status: models.ChangeAdded, Path: "src/api/handler.go"},
+				{Status: models.ChangeModified, Path: "src/api/main.go"},
+				{Status: models.ChangeDeleted, Path: "src/web/index.js"},
+			},
+		},
+		{
+			"exact rename",
+			func(repo *testRepo) string {
+				base := repo.Git("rev-parse", "HEAD")
+				repo.Git("mv", "src/web/index.js", "src/web/main.js")
+				repo.Commit("rename")
+
+				return base
+			},
+			func(provider Provider, base string) (interface{}, error) {
+				return provider.DiffCommits(base, "HEAD")
+			},
+			[]models.ChangedFile{
+				{Status: models.ChangeRenamed, Path: "src/web/main.js", OldPath: "src/web/index.js"},
+			},
+		},
+		{
+			"path with spaces",
+			func(repo *testRepo) string {
+				base := repo.Git("rev-parse", "HEAD")
+				repo.Write("docs/read me.md", "docs\n")
+				repo.Commit("docs")
+
+				return base
+			},
+			func(provider Provider, base string) (interface{}, error) {
+				return provider.DiffCommits(base, "HEAD")
+			},
+			[]models.ChangedFile{
+				{Status: models.ChangeAdded, Path: "docs/read me.md"},
+			},
+		},
+		{
+			"worktree",
+			func(repo *testRepo) string {
+				base := repo.Git("rev-parse", "HEAD")
+				repo.Write("src/api/main.go", "package main\n")
+				repo.Write("src/web/index.js", "console.log('staged')\n")
+				repo.Git("add", "src/web/index.js")
+
+				return base
+			},
+			func(provider Provider, base string) (interface{}, error) {
+				files, err := provider.DiffWorktree(base, false)
+				return sortFiles(files), err
+			},
+			[]models.ChangedFile{
+				{Status: models.ChangeModified, Path: "src/api/main.go"},
+				{Status: models.ChangeModified, Path: "src/web/index.js"},
+			},
+		},
+		{
+			"staged",
+			func(repo *testRepo) string {
+				base := repo.Git("rev-parse", "HEAD")
+				repo.Write("src/api/main.go", "package main\n")
+				repo.Write("src/web/index.js", "console.log('staged')\n")
+				repo.Git("add", "src/web/index.js")
+
+				return base
+			},
+			func(provider Provider, base string) (interface{}, error) {
+				return provider.DiffWorktree(base, true)
+			},
+			[]models.ChangedFile{
+				{Status: models.ChangeModified, Path: "src/web/index.js"},
+			},
+		},
+		{
+			"untracked",
+			func(repo *testRepo) string {
+				repo.Write(".gitignore", "*.log\n")
+				repo.Commit("ignore logs")
+				repo.Write("src/api/new.go", "package main\n")
+				repo.Write("src/api/debug.log", "log\n")
+
+				return ""
+			},
+			func(provider Provider, base string) (interface{}, error) {
+				return provider.Untracked()
+			},
+			[]string{"src/api/new.go"},
+		},
+		{
+			"crlf checkout with autocrlf",
+			func(repo *testRepo) string {
+				repo.Git("config", "core.autocrlf", "true")
+				repo.Write("src/api/main.go", "package main\r\n\r\nfunc main() {\r\n}\r\n")
+
+				return ""
+			},
+			func(provider Provider, base string) (interface{}, error) {
+				return provider.Unstaged()
+			},
+			[]models.ChangedFile(nil),
+		},
+		{
+			"crlf checkout with text attribute",
+			func(repo *testRepo) string {
+				repo.Write(".gitattributes", "*.js text\n")
+				repo.Commit("attributes")
+				repo.Write("src/web/index.js", "console.log('web')\r\n")
+				repo.Write("src/api/main.go", "package main\r\n\r\nfunc main() {\r\n}\r\n")
+
+				return ""
+			},
+			func(provider Provider, base string) (interface{}, error) {
+				return provider.Unstaged()
+			},
+			[]models.ChangedFile{
+				{Status: models.ChangeModified, Path: "src/api/main.go"},
+			},
+		},
+		{
+			"crlf committed with autocrlf",
+			func(repo *testRepo) string {
+				repo.Write("src/web/index.js", "console.log('web')\r\n")
+				repo.Commit("crlf")
+				repo.Git("config", "core.autocrlf", "true")
+				repo.Write("src/web/index.js", "console.log('web')\r\nconsole.log('more')\r\n")
+
+				return ""
+			},
+			func(provider Provider, base string) (interface{}, error) {
+				return provider.Unstaged()
+			},
+			[]models.ChangedFile{
+				{Status: models.ChangeModified, Path: "src/web/index.js"},
+			},
+		},
+		{
+			"changed lines",
+			func(repo *testRepo) string {
+				base := repo.Git("rev-parse", "HEAD")
+				repo.Write("src/api/main.go", "package main\n\n\nfunc main()  {\n\tprintln()\n}\n")
+				repo.Commit("change")
+
+				return base
+			},
+			func(provider Provider, base string) (interface{}, error) {
+				return provider.ChangedLines(base, "HEAD", false, "src/api/main.go")
+			},
+			[]string{"\tprintln()"},
+		},
+		{
+			"commit message and merge base",
+			func(repo *testRepo) string {
+				base := repo.Git("rev-parse", "HEAD")
+				repo.Git("checkout", "-q", "-b", "feature")
+				repo.Write("src/api/main.go", "package main\n")
+				repo.Commit("change the api\n\n[skip mrbuild]")
+
+				return base
+			},
+			func(provider Provider, base string) (interface{}, error) {
+				message, err := provider.CommitMessage("HEAD")
+				if err != nil {
+					return nil, err
+				}
+
+				mergeBase, err := provider.MergeBase("main")
+				if err != nil {
+					return nil, err
+				}
+
+				parent, err := provider.ResolveRef("HEAD^")
+
+				return []interface{}{message, mergeBase == base, parent == base}, err
+			},
+			[]interface{}{"change the api\n\n[skip mrbuild]", true, true},
+		},
+	}
+
+	for _, table := range tables {
+		repo := newTestRepo(t)
+		base := table.setup(repo)
+
+		for name, provider := range repo.providers() {
+			actual, err := table.read(provider, base)
+
+			assert.NoError(t, err, "%s: %s", table.name, name)
+			assert.Equal(t, table.expected, actual, "%s: %s", table.name, name)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/amido/mrbuild/internal/config"
+)
+
+// Graph holds the dependencies between the projects, as declared by depends_on
+// The projects are held in the order in which they are declared so that walking the
+// graph always gives the same result
+type Graph struct {
+	names        []string
+	orders       map[string]int
+	dependencies map[string][]string
+	dependents   map[string][]string
+}
+
+// New builds the graph of the projects. An error is returned if a project depends on a
+// project that does not exist, or that has a higher order, or if the dependencies contain a cycle
+// The projects are validated as a whole, so that the error does not depend on which projects
+// have been affected
+func New(projects []config.Project) (*Graph, error) {
+
+	g := &Graph{
+		orders:       make(map[string]int),
+		dependencies: make(map[string][]string),
+		dependents:   make(map[string][]string),
+	}
+
+	for i := range projects {
+		project := &projects[i]
+
+		if _, ok := g.dependencies[project.Name]; !ok {
+			g.names = append(g.names, project.Name)
+		}
+		g.dependencies[project.Name] = append(g.dependencies[project.Name], project.DependsOn...)
+		g.orders[project.Name] = project.Order
+	}
+
+	for _, name := range g.names {
+		for _, dependency := range g.dependencies[name] {
+			if _, ok := g.dependencies[dependency]; !ok {
+				return nil, fmt.Errorf("project %s depends on unknown project %s", name, dependency)
+			}
+
+			g.dependents[dependency] = append(g.dependents[dependency], name)
+		}
+	}
+
+	// the order tiers are built in turn, so a project cannot be built after a project in a higher tier
+	for _, name := range g.names {
+		for _, dependency := range g.dependencies[name] {
+			if g.orders[dependency] > g.orders[name] {
+				return nil, fmt.Errorf("project %s depends on %s, which has a higher order", name, dependency)
+			}
+		}
+	}
+
+	if cycle := g.findCycle(); len(cycle) > 0 {
+		return nil, fmt.Errorf("dependency cycle between projects: %s", strings.Join(cycle, " -> "))
+	}
+
+	return g, nil
+}
+
+// Dependencies returns the projects that the project depends on directly
+func (g *Graph) Dependencies(name string) []string {
+	return g.dependencies[name]
+}
+
+// AllDependencies returns the projects that the project depends on, directly or transitively
+func (g *Graph) AllDependencies(name string) []string {
+
+	var result []string
+	seen := map[string]bool{name: true}
+
+	queue := append([]string{}, g.dependencies[name]...)
+	for len(queue) > 0 {
+		dependency := queue[0]
+		queue = queue[1:]
+
+		if seen[dependency] {
+			continue
+		}
+		seen[dependency] = true
+
+		result = append(result, dependency)
+		queue = append(queue, g.dependencies[dependency]...)
+	}
+
+	return result
+}
+
+// Dependents returns the projects that depend directly on the project
+func (g *Graph) Dependents(name string) []string {
+	return g.dependents[name]
+}
+
+// Propagate returns each project that depends, directly or transitively, on one of the
+// affected projects and is not affected itself. Each project is mapped to the chain of
+// projects that it is affected via, nearest first, e.g. web is affected via [api lib-common]
+// The shortest chain is used, with ties going to the project that is declared first
+func (g *Graph) Propagate(affected []string) map[string][]string {
+
+	chains := make(map[string][]string)
+
+	seen := make(map[string]bool)
+	for _, name := range affected {
+		seen[name] = true
+	}
+
+	queue := append([]string{}, affected...)
+
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+
+		for _, dependent := range g.dependents[name] {
+			if seen[dependent] {
+				continue
+			}
+			seen[dependent] = true
+
+			chains[dependent] = append([]string{name}, chains[name]...)
+			queue = append(queue, dependent)
+		}
+	}
+
+	return chains
+}
+
+// findCycle returns the projects that form a cycle, with the first project repeated at
+// the end, or nil if there are no cycles
+func (g *Graph) findCycle() []string {
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := make(map[string]int)
+	var path []string
+
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		state[name] = visiting
+		path = append(path, name)
+
+		for _, dependency := range g.dependencies[name] {
+			switch state[dependency] {
+			case visiting:
+				for i, item := range path {
+					if item == dependency {
+						return append(append([]string{}, path[i:]...), dependency)
+					}
+				}
+			case unvisited:
+				if cycle := visit(dependency); cycle != nil {
+					return cycle
+				}
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[name] = visited
+
+		return nil
+	}
+
+	for _, name := range g.names {
+		if state[name] == unvisited {
+			if cycle := visit(name); cycle != nil {
+				return cycle
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/amido/mrbuild/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+
+	tables := []struct {
+		name     string
+		projects []config.Project
+		err      string
+	}{
+		{
+			"valid dependencies",
+			[]config.Project{{Name: "lib"}, {Name: "api", DependsOn: []string{"lib"}}},
+			"",
+		},
+		{
+			"unknown dependency",
+			[]config.Project{{Name: "api", DependsOn: []string{"lib"}}},
+			"project api depends on unknown project lib",
+		},
+		{
+			"dependency in a higher order",
+			[]config.Project{{Name: "api", DependsOn: []string{"lib"}}, {Name: "lib", Order: 1}},
+			"project api depends on lib, which has a higher order",
+		},
+		{
+			"dependency in a lower order",
+			[]config.Project{{Name: "lib"}, {Name: "api", Order: 1, DependsOn: []string{"lib"}}},
+			"",
+		},
+		{
+			"self dependency",
+			[]config.Project{{Name: "api", DependsOn: []string{"api"}}},
+			"dependency cycle between projects: api -> api",
+		},
+		{
+			"cycle",
+			[]config.Project{
+				{Name: "web", DependsOn: []string{"api"}},
+				{Name: "api", DependsOn: []string{"lib"}},
+				{Name: "lib", DependsOn: []string{"web"}},
+			},
+			"dependency cycle between projects: web -> api -> lib -> web",
+		},
+	}
+
+	for _, table := range tables {
+		_, err := New(table.projects)
+
+		if table.err == "" {
+			assert.NoError(t, err, table.name)
+		} else {
+			assert.EqualError(t, err, table.err, table.name)
+		}
+	}
+}
+
+func TestPropagate(t *testing.T) {
+
+	g, err := New([]config.Project{
+		{Name: "lib-common"},
+		{Name: "lib-auth", DependsOn: []string{"lib-common"}},
+		{Name: "api", DependsOn: []string{"lib-common", "lib-auth"}},
+		{Name: "web", DependsOn: []string{"api"}},
+		{Name: "docs"},
+	})
+	assert.NoError(t, err)
+
+	chains := g.Propagate([]string{"lib-common"})
+	assert.Equal(t, map[string][]string{
+		"lib-auth": {"lib-common"},
+		"api":      {"lib-common"},
+		"web":      {"api", "lib-common"},
+	}, chains)
+
+	chains = g.Propagate([]string{"lib-auth", "web"})
+	assert.Equal(t, map[string][]string{
+		"api": {"lib-auth"},
+	}, chains, "Projects that are already affected should not be included")
+
+	assert.Equal(t, []string{"lib-common", "lib-auth"}, g.Dependencies("api"))
+	assert.Equal(t, []string{"lib-auth", "api"}, g.Dependents("lib-common"))
+}
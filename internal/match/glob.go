@@ -0,0 +1,202 @@
+package match
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// Globs is a list of doublestar glob patterns that are matched against the paths of files
+// A pattern that starts with ! excludes the files that it matches. The patterns are checked
+// in order and the last one that matches a file decides if it is included, in the same
+// way as a .gitignore file
+type Globs struct {
+	rules []globRule
+}
+
+// globRule is a single glob pattern, with the prefix already applied
+type globRule struct {
+	pattern string
+	negate  bool
+}
+
+// CompileGlobs checks that each of the patterns is valid and prefixes them with the folder
+// so that they can be matched against paths relative to the root of the repository
+// The folder is matched literally, so a folder such as src/[id] is not treated as a pattern
+// If all of the patterns are exclusions then all other files in the folder are included
+func CompileGlobs(folder string, patterns []string) (*Globs, error) {
+
+	globs := &Globs{}
+	positive := false
+
+	prefix := escape(strings.Trim(path.Clean("/"+strings.ReplaceAll(folder, "\\", "/")), "/"))
+
+	for _, pattern := range patterns {
+		rule := globRule{}
+
+		pattern = strings.TrimSpace(pattern)
+		if strings.HasPrefix(pattern, "!") {
+			rule.negate = true
+			pattern = strings.TrimPrefix(pattern, "!")
+		} else {
+			positive = true
+		}
+
+		pattern = strings.TrimPrefix(pattern, "/")
+		if prefix != "" {
+			pattern = prefix + "/" + pattern
+		}
+
+		if pattern == "" || !doublestar.ValidatePattern(pattern) {
+			return nil, fmt.Errorf("invalid glob pattern '%s'", pattern)
+		}
+
+		rule.pattern = pattern
+		globs.rules = append(globs.rules, rule)
+	}
+
+	// only exclusions have been set, so include everything else in the folder
+	if !positive && len(globs.rules) > 0 {
+		all := "**"
+		if prefix != "" {
+			all = prefix + "/**"
+		}
+
+		globs.rules = append([]globRule{{pattern: all}}, globs.rules...)
+	}
+
+	return globs, nil
+}
+
+// Match determines if the path is included by the globs
+func (g *Globs) Match(file string) bool {
+
+	matched := false
+
+	for _, rule := range g.rules {
+		if rule.negate != matched {
+			continue
+		}
+
+		if ok, _ := doublestar.Match(rule.pattern, file); ok {
+			matched = !rule.negate
+		}
+	}
+
+	return matched
+}
+
+// Empty states if there are no patterns
+func (g *Globs) Empty() bool {
+	return g == nil || len(g.rules) == 0
+}
+
+// escape escapes the characters in the folder that have a special meaning in a glob
+func escape(folder string) string {
+
+	var escaped strings.Builder
+
+	for _, char := range folder {
+		if strings.ContainsRune("\\*?[]{}", char) {
+			escaped.WriteRune('\\')
+		}
+
+		escaped.WriteRune(char)
+	}
+
+	return escaped.String()
+}
+
+// CompileExcludes compiles the patterns for the files that should be excluded from a folder
+// The patterns follow the rules of a .gitignore file, so a pattern without a slash, such
+// as README.md or *_test.fixture, matches at any depth and a pattern that ends with a
+// slash, such as docs/, matches everything in the directory
+func CompileExcludes(folder string, patterns []string) (*Globs, error) {
+
+	var globs []string
+
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+
+		if strings.HasPrefix(pattern, "!") {
+			return nil, fmt.Errorf("exclude pattern cannot be negated '%s'", pattern)
+		}
+
+		// a directory excludes all of the files within it
+		if strings.HasSuffix(pattern, "/") {
+			pattern += "**"
+		}
+
+		// a pattern without a slash, other than at the end, can match at any depth
+		if !strings.Contains(strings.TrimSuffix(pattern, "/**"), "/") {
+			pattern = "**/" + pattern
+		}
+
+		globs = append(globs, pattern)
+	}
+
+	return CompileGlobs(folder, globs)
+}
+
+// CompileInputs compiles patterns that are relative to the root of the repository
+// A pattern matches a file with the same path, or any file below a directory with the
+// same path, so infra/modules/network matches all of the files in that directory
+func CompileInputs(patterns []string) (*Globs, error) {
+
+	var globs []string
+
+	for _, pattern := range patterns {
+		pattern = strings.TrimSuffix(strings.TrimSpace(pattern), "/")
+		globs = append(globs, pattern, pattern+"/**")
+	}
+
+	return CompileGlobs("", globs)
+}
+
+// Base returns the directory at the start of the pattern that does not contain any
+// wildcards, or an empty string if the pattern is negated
+func Base(pattern string) string {
+
+	pattern = strings.TrimSpace(pattern)
+	if strings.HasPrefix(pattern, "!") {
+		return ""
+	}
+
+	base, _ := doublestar.SplitPattern(strings.TrimPrefix(pattern, "/"))
+
+	return base
+}
+
+// Rule is a single glob pattern, after the folder has been applied, and states if it
+// matched a file. A negated pattern starts with !
+type Rule struct {
+	Pattern string
+	Matched bool
+}
+
+// Explain returns each of the patterns, in order, and if it matches the file
+// The patterns are not combined, so a negated pattern is reported as matching if the
+// file would be excluded by it
+func (g *Globs) Explain(file string) []Rule {
+
+	var rules []Rule
+
+	if g == nil {
+		return rules
+	}
+
+	for _, rule := range g.rules {
+		ok, _ := doublestar.Match(rule.pattern, file)
+
+		pattern := rule.pattern
+		if rule.negate {
+			pattern = "!" + pattern
+		}
+
+		rules = append(rules, Rule{Pattern: pattern, Matched: ok})
+	}
+
+	return rules
+}
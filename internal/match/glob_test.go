@@ -0,0 +1,146 @@
+package match
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGlobsMatch(t *testing.T) {
+
+	tables := []struct {
+		name     string
+		folder   string
+		patterns []string
+		path     string
+		expected bool
+	}{
+		{"extension in folder", "src/infra", []string{"**/*.tf"}, "src/infra/main.tf", true},
+		{"extension in subfolder", "src/infra", []string{"**/*.tf"}, "src/infra/modules/vpc/main.tf", true},
+		{"different extension", "src/infra", []string{"**/*.tf"}, "src/infra/README.md", false},
+		{"outside folder", "src/infra", []string{"**/*.tf"}, "src/other/main.tf", false},
+		{"folder prefix of another", "src/api", []string{"**"}, "src/api-gateway/main.go", false},
+		{"single star does not cross folders", "src/api", []string{"*.go"}, "src/api/cmd/main.go", false},
+		{"negation", "src/api", []string{"**", "!**/*.md"}, "src/api/README.md", false},
+		{"negation does not exclude others", "src/api", []string{"**", "!**/*.md"}, "src/api/main.go", true},
+		{"only negations", "src/api", []string{"!**/*.md", "!docs/**"}, "src/api/main.go", true},
+		{"only negations excluded", "src/api", []string{"!**/*.md", "!docs/**"}, "src/api/docs/index.html", false},
+		{"last pattern wins", "src/api", []string{"**/*.md", "!**/*.md", "CHANGELOG.md"}, "src/api/CHANGELOG.md", true},
+		{"root folder", ".", []string{"*.yaml"}, "mrbuild.yaml", true},
+		{"backslash folder", "src\\api", []string{"**/*.go"}, "src/api/main.go", true},
+		{"braces", "src/web", []string{"**/*.{js,ts}"}, "src/web/app.ts", true},
+		{"bracketed folder", "app/[id]", []string{"**/*.tsx"}, "app/[id]/page.tsx", true},
+		{"bracketed folder is not a class", "app/[id]", []string{"**/*.tsx"}, "app/i/page.tsx", false},
+		{"wildcard in folder", "src/*", []string{"**"}, "src/api/main.go", false},
+		{"braces in folder", "src/{a,b}", []string{"**"}, "src/{a,b}/main.go", true},
+	}
+
+	for _, table := range tables {
+		globs, err := CompileGlobs(table.folder, table.patterns)
+		assert.NoError(t, err, table.name)
+
+		assert.Equal(t, table.expected, globs.Match(table.path), table.name)
+	}
+}
+
+func TestCompileGlobs(t *testing.T) {
+
+	globs, err := CompileGlobs("src", nil)
+	assert.NoError(t, err)
+	assert.True(t, globs.Empty(), "No patterns should be empty")
+	assert.False(t, globs.Match("src/main.go"), "Empty globs should not match")
+
+	_, err = CompileGlobs("src", []string{"**/[a-z.tf"})
+	assert.Error(t, err, "An invalid pattern should be reported")
+}
+
+func TestCompileExcludes(t *testing.T) {
+
+	patterns := []string{"README.md", "docs/", "*_test.fixture", "/build/*.log"}
+
+	tables := []struct {
+		path     string
+		expected bool
+	}{
+		{"src/api/README.md", true},
+		{"src/api/internal/README.md", true},
+		{"src/api/docs/usage.md", true},
+		{"src/api/internal/docs/usage.md", true},
+		{"src/api/testdata/users_test.fixture", true},
+		{"src/api/build/output.log", true},
+		{"src/api/cmd/build/output.log", false},
+		{"src/api/main.go", false},
+		{"src/other/README.md", false},
+	}
+
+	excludes, err := CompileExcludes("src/api", patterns)
+	assert.NoError(t, err)
+
+	for _, table := range tables {
+		assert.Equal(t, table.expected, excludes.Match(table.path), table.path)
+	}
+
+	excludes, err = CompileExcludes("app/[id]", []string{"README.md"})
+	assert.NoError(t, err)
+	assert.True(t, excludes.Match("app/[id]/README.md"), "The folder should be matched literally")
+	assert.False(t, excludes.Match("app/d/README.md"), "The folder should not be treated as a pattern")
+
+	_, err = CompileExcludes("src/api", []string{"!README.md"})
+	assert.Error(t, err, "A negated exclude should be reported")
+}
+
+func TestCompileInputs(t *testing.T) {
+
+	patterns := []string{"shared/proto/**/*.proto", "infra/modules/network", "libs/common/", "go.mod"}
+
+	tables := []struct {
+		path     string
+		expected bool
+	}{
+		{"shared/proto/api/v1/users.proto", true},
+		{"shared/proto/README.md", false},
+		{"infra/modules/network/main.tf", true},
+		{"infra/modules/network-legacy/main.tf", false},
+		{"libs/common/util.go", true},
+		{"go.mod", true},
+		{"src/api/go.mod", false},
+	}
+
+	inputs, err := CompileInputs(patterns)
+	assert.NoError(t, err)
+
+	for _, table := range tables {
+		assert.Equal(t, table.expected, inputs.Match(table.path), table.path)
+	}
+}
+
+func TestBase(t *testing.T) {
+
+	tables := []struct {
+		pattern  string
+		expected string
+	}{
+		{"shared/proto/**", "shared/proto"},
+		{"infra/modules/network", "infra/modules"},
+		{"/libs/*/src", "libs"},
+		{"!shared/proto/internal/**", ""},
+	}
+
+	for _, table := range tables {
+		assert.Equal(t, table.expected, Base(table.pattern), table.pattern)
+	}
+}
+
+func TestGlobsExplain(t *testing.T) {
+
+	globs, err := CompileGlobs("src/api", []string{"!docs/**"})
+	assert.NoError(t, err)
+
+	expected := []Rule{
+		{Pattern: "src/api/**", Matched: true},
+		{Pattern: "!src/api/docs/**", Matched: true},
+	}
+
+	assert.Equal(t, expected, globs.Explain("src/api/docs/index.md"))
+	assert.Empty(t, (*Globs)(nil).Explain("src/api/main.go"))
+}
@@ -0,0 +1,50 @@
+package models
+
+const (
+	// ChangeAdded states that the file has been added
+	ChangeAdded = "A"
+
+	// ChangeModified states that the file has been modified
+	ChangeModified = "M"
+
+	// ChangeDeleted states that the file has been deleted
+	ChangeDeleted = "D"
+
+	// ChangeRenamed states that the file has been renamed, the previous path is held in OldPath
+	ChangeRenamed = "R"
+
+	// ChangeCopied states that the file has been copied, the source path is held in OldPath
+	ChangeCopied = "C"
+)
+
+// ChangedFile holds the details of a file that has been changed
+type ChangedFile struct {
+	Status  string // Status of the change, as reported by git, e.g. A, M, D or R
+	Path    string // Path to the file relative to the root of the repository
+	OldPath string // Previous path to the file if it has been renamed or copied
+}
+
+// GetPaths returns the paths that the change affects
+// For a rename this is both the old and the new path so that the projects that
+// own each of them are considered to have changed
+func (c *ChangedFile) GetPaths() []string {
+	if c.Status == ChangeRenamed && c.OldPath != "" {
+		return []string{c.OldPath, c.Path}
+	}
+
+	return []string{c.Path}
+}
+
+// GetRemovedPath returns the path that no longer exists as a result of the change
+// This is the path of a deleted file or the old path of a renamed file, otherwise
+// an empty string is returned
+func (c *ChangedFile) GetRemovedPath() string {
+	switch c.Status {
+	case ChangeDeleted:
+		return c.Path
+	case ChangeRenamed:
+		return c.OldPath
+	}
+
+	return ""
+}
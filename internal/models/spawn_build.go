@@ -4,12 +4,36 @@ import (
 	"strings"
 )
 
+const (
+	// AffectedFilesEnvVar is the name of the environment variable that contains the files, one
+	// per line, that caused the project to be affected
+	AffectedFilesEnvVar = "MRBUILD_AFFECTED_FILES"
+
+	// AffectedFilesPathEnvVar is the name of the environment variable that contains the path to
+	// a file that lists the files, one per line, that caused the project to be affected
+	AffectedFilesPathEnvVar = "MRBUILD_AFFECTED_FILES_PATH"
+
+	// MaxAffectedFilesEnvSize is the size of the largest list of files that is passed in the
+	// environment variable, as the size of each variable is limited, e.g. to 128 KiB on Linux
+	MaxAffectedFilesEnvSize = 64 * 1024
+
+	// BuildAllEnvVar is the name of the environment variable that contains the reason that all
+	// of the projects are being built, such as a global trigger or a shallow clone in which the
+	// changes cannot be determined. It is not set when only the affected projects are built
+	BuildAllEnvVar = "MRBUILD_BUILD_ALL"
+)
+
 type SpawnBuild struct {
-	Name      string // Name of the project in the mono repo
-	Directory string // Directory in which the the command should be run
-	Command   string // Command to run
+	Name      string   // Name of the project in the mono repo
+	Directory string   // Directory in which the the command should be run
+	Command   string   // Command to run
+	Commands  []string // Commands to run, in order, when the project has groups of commands
 	Env       map[string]string
 	Order     int
+	Deleted   bool     // States if the project has been removed and the command is the on_delete command
+	Files     []string // Files that caused the project to be affected
+	DependsOn []string // Projects that must be built successfully before this one, directly or transitively
+	BuildAll  string   // Reason that all of the projects are being built, if they are
 }
 
 // GetCommand returns a single string containing the command and the arguments that should be executed
@@ -17,9 +41,63 @@ func (s *SpawnBuild) GetCommand() string {
 	return s.Command
 }
 
+// GetCommands returns each of the commands that should be run, in order
+func (s *SpawnBuild) GetCommands() []string {
+
+	if len(s.Commands) > 0 {
+		return s.Commands
+	}
+
+	if s.Command == "" {
+		return nil
+	}
+
+	return []string{s.Command}
+}
+
 // GetCommandParts returns the command and the arguments to the calling function
 func (s *SpawnBuild) GetCommandParts() (string, string) {
-	cmdParts := strings.SplitN(s.Command, " ", 2)
+	return CommandParts(s.Command)
+}
+
+// CommandParts splits the command into the executable and its arguments
+func CommandParts(command string) (string, string) {
+	cmdParts := strings.SplitN(command, " ", 2)
+
+	if len(cmdParts) == 1 {
+		return cmdParts[0], ""
+	}
 
 	return cmdParts[0], cmdParts[1]
 }
+
+// GetEnv returns the environment variables that should be set for the command, which
+// are those of the project and the list of files that caused the project to be affected
+// The list of files is left out if it is too large to be passed in a variable
+func (s *SpawnBuild) GetEnv() map[string]string {
+
+	env := make(map[string]string)
+	for name, value := range s.Env {
+		env[name] = value
+	}
+
+	if s.AffectedFilesFit() {
+		env[AffectedFilesEnvVar] = s.AffectedFiles()
+	}
+
+	if s.BuildAll != "" {
+		env[BuildAllEnvVar] = s.BuildAll
+	}
+
+	return env
+}
+
+// AffectedFiles returns the files that caused the project to be affected, one per line
+func (s *SpawnBuild) AffectedFiles() string {
+	return strings.Join(s.Files, "\n")
+}
+
+// AffectedFilesFit states if the list of files is small enough to be passed in an environment variable
+func (s *SpawnBuild) AffectedFilesFit() bool {
+	return len(s.AffectedFiles()) <= MaxAffectedFilesEnvSize
+}
@@ -1,6 +1,9 @@
 package models
 
-import "testing"
+import (
+	"fmt"
+	"testing"
+)
 
 func TestGetCommand(t *testing.T) {
 
@@ -64,3 +67,71 @@ func TestGetCommandParts(t *testing.T) {
 		}
 	}
 }
+
+func TestGetEnv(t *testing.T) {
+
+	sb := SpawnBuild{
+		Env:   map[string]string{"STAGE": "dev"},
+		Files: []string{"src/api/main.go", "src/api/go.mod"},
+	}
+
+	env := sb.GetEnv()
+
+	if env["STAGE"] != "dev" {
+		t.Error("Environment variable of the project has not been set")
+	}
+
+	if env[AffectedFilesEnvVar] != "src/api/main.go\nsrc/api/go.mod" {
+		t.Error("Affected files have not been set")
+	}
+
+	if _, ok := sb.Env[AffectedFilesEnvVar]; ok {
+		t.Error("Environment variables of the project have been modified")
+	}
+
+	if _, ok := env[BuildAllEnvVar]; ok {
+		t.Error("The build all variable should not be set when only the affected projects are built")
+	}
+
+	sb.BuildAll = "changes cannot be determined in shallow clone"
+	if sb.GetEnv()[BuildAllEnvVar] != sb.BuildAll {
+		t.Error("The reason that all of the projects are being built has not been set")
+	}
+}
+
+func TestGetEnvLargeFileList(t *testing.T) {
+
+	sb := SpawnBuild{
+		Files: make([]string, 5000),
+	}
+
+	for i := range sb.Files {
+		sb.Files[i] = fmt.Sprintf("src/api/internal/handlers/generated/handler_%04d.go", i)
+	}
+
+	if sb.AffectedFilesFit() {
+		t.Error("The list of files should be too large for an environment variable")
+	}
+
+	if _, ok := sb.GetEnv()[AffectedFilesEnvVar]; ok {
+		t.Error("The list of files should not be passed in an environment variable")
+	}
+}
+
+func TestGetCommands(t *testing.T) {
+
+	sb := SpawnBuild{Command: "make build"}
+	if commands := sb.GetCommands(); len(commands) != 1 || commands[0] != "make build" {
+		t.Error("The command should be used when there are no commands")
+	}
+
+	sb.Commands = []string{"make build", "make docs"}
+	if commands := sb.GetCommands(); len(commands) != 2 || commands[1] != "make docs" {
+		t.Error("The commands should be used when they have been set")
+	}
+
+	cmd, args := CommandParts("make")
+	if cmd != "make" || args != "" {
+		t.Error("A command without arguments should not have any arguments")
+	}
+}
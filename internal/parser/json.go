@@ -0,0 +1,157 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/amido/mrbuild/internal/models"
+)
+
+// gitHubFile is a file from the GitHub "list pull request files" API
+type gitHubFile struct {
+	Filename         string `json:"filename"`
+	Status           string `json:"status"`
+	PreviousFilename string `json:"previous_filename"`
+}
+
+// gitLabChange is a change from the GitLab merge request changes, or diffs, API
+type gitLabChange struct {
+	OldPath     string `json:"old_path"`
+	NewPath     string `json:"new_path"`
+	NewFile     bool   `json:"new_file"`
+	RenamedFile bool   `json:"renamed_file"`
+	DeletedFile bool   `json:"deleted_file"`
+}
+
+// ParseJSON converts a JSON array of paths into a slice of changed files
+// Each file is treated as modified
+func ParseJSON(data []byte) ([]models.ChangedFile, error) {
+
+	var files []models.ChangedFile
+
+	err := decodeArrays(data, func(decoder *json.Decoder) error {
+		var paths []string
+
+		if err := decoder.Decode(&paths); err != nil {
+			return err
+		}
+
+		for _, path := range paths {
+			files = append(files, models.ChangedFile{Status: models.ChangeModified, Path: path})
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse JSON array of paths: %s", err.Error())
+	}
+
+	return files, nil
+}
+
+// ParseGitHub converts the JSON from the GitHub "list pull request files" API into a slice
+// of changed files. The output of a paginated request, which is a number of concatenated
+// arrays, is supported
+func ParseGitHub(data []byte) ([]models.ChangedFile, error) {
+
+	var files []models.ChangedFile
+
+	err := decodeArrays(data, func(decoder *json.Decoder) error {
+		var page []gitHubFile
+
+		if err := decoder.Decode(&page); err != nil {
+			return err
+		}
+
+		for _, item := range page {
+			file := models.ChangedFile{Status: models.ChangeModified, Path: item.Filename}
+
+			switch item.Status {
+			case "added":
+				file.Status = models.ChangeAdded
+			case "removed":
+				file.Status = models.ChangeDeleted
+			case "renamed":
+				file.Status = models.ChangeRenamed
+				file.OldPath = item.PreviousFilename
+			case "copied":
+				file.Status = models.ChangeCopied
+				file.OldPath = item.PreviousFilename
+			}
+
+			files = append(files, file)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse GitHub pull request files: %s", err.Error())
+	}
+
+	return files, nil
+}
+
+// ParseGitLab converts the JSON from the GitLab merge request changes API into a slice
+// of changed files. Either the merge request object with the `changes` attribute or the
+// array of changes from the diffs API is supported
+func ParseGitLab(data []byte) ([]models.ChangedFile, error) {
+
+	var files []models.ChangedFile
+	var changes []gitLabChange
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &changes); err != nil {
+			return nil, fmt.Errorf("unable to parse GitLab merge request changes: %s", err.Error())
+		}
+	} else {
+		var mr struct {
+			Changes []gitLabChange `json:"changes"`
+		}
+
+		if err := json.Unmarshal(trimmed, &mr); err != nil {
+			return nil, fmt.Errorf("unable to parse GitLab merge request changes: %s", err.Error())
+		}
+
+		changes = mr.Changes
+	}
+
+	for _, change := range changes {
+		file := models.ChangedFile{Status: models.ChangeModified, Path: change.NewPath}
+
+		switch {
+		case change.NewFile:
+			file.Status = models.ChangeAdded
+		case change.DeletedFile:
+			file.Status = models.ChangeDeleted
+			file.Path = change.OldPath
+		case change.RenamedFile:
+			file.Status = models.ChangeRenamed
+			file.OldPath = change.OldPath
+		}
+
+		files = append(files, file)
+	}
+
+	return files, nil
+}
+
+// decodeArrays calls the decode function for each of the JSON values in the data
+// so that concatenated arrays, such as those from paginated API calls, can be read
+func decodeArrays(data []byte, decode func(decoder *json.Decoder) error) error {
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+
+	for {
+		err := decode(decoder)
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
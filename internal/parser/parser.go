@@ -0,0 +1,165 @@
+package parser
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/amido/mrbuild/internal/config"
+	"github.com/amido/mrbuild/internal/models"
+)
+
+// statusPattern matches the status of a file from `git diff --name-status`, including
+// the similarity score of renames and copies, e.g. R100
+var statusPattern = regexp.MustCompile(`^[ACDMRTUXB][0-9]*$`)
+
+// Parse converts the data into a list of changed files according to the specified format
+// All of the paths are normalised so that they are relative to the root of the repository
+// and use forward slashes
+func Parse(format string, data []byte) ([]models.ChangedFile, error) {
+
+	var files []models.ChangedFile
+	var err error
+
+	switch format {
+	case config.InputFormatLines, "":
+		files = ParseLines(string(data))
+	case config.InputFormatNul:
+		files = ParseNul(string(data))
+	case config.InputFormatPorcelain:
+		files, err = ParsePorcelain(string(data))
+	case config.InputFormatPorcelainV2:
+		files, err = ParsePorcelainV2(string(data))
+	case config.InputFormatJSON:
+		files, err = ParseJSON(data)
+	case config.InputFormatGitHub:
+		files, err = ParseGitHub(data)
+	case config.InputFormatGitLab:
+		files, err = ParseGitLab(data)
+	default:
+		err = fmt.Errorf("unknown input format '%s'", format)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return normaliseFiles(files), nil
+}
+
+// ParseLines converts a newline delimited list of files into a slice of changed files
+// If the lines contain the status from `git diff --name-status` it is used, otherwise
+// each file is treated as modified
+func ParseLines(data string) []models.ChangedFile {
+
+	var files []models.ChangedFile
+
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		parts := strings.Split(line, "\t")
+		if len(parts) > 1 && statusPattern.MatchString(parts[0]) {
+			files = append(files, ParseNameStatus(line)...)
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		files = append(files, models.ChangedFile{
+			Status: models.ChangeModified,
+			Path:   strings.TrimSpace(line),
+		})
+	}
+
+	return files
+}
+
+// ParseNameStatus converts the output of `git diff --name-status` into a slice of changed files
+// Renames and copies have a similarity score appended to the status, e.g. R100, and contain
+// both the old and the new path
+func ParseNameStatus(data string) []models.ChangedFile {
+
+	var files []models.ChangedFile
+
+	for _, line := range strings.Split(data, "\n") {
+		parts := strings.Split(strings.TrimRight(line, "\r"), "\t")
+		if len(parts) < 2 || parts[0] == "" {
+			continue
+		}
+
+		file := models.ChangedFile{
+			Status: parts[0][:1],
+			Path:   parts[1],
+		}
+
+		if len(parts) > 2 {
+			file.OldPath = parts[1]
+			file.Path = parts[2]
+		}
+
+		files = append(files, file)
+	}
+
+	return files
+}
+
+// ParseNul converts the NUL delimited output of `git diff -z` into a slice of changed files
+// The output can be from either `--name-only` or `--name-status`
+func ParseNul(data string) []models.ChangedFile {
+
+	var files []models.ChangedFile
+
+	tokens := strings.Split(strings.TrimRight(data, "\x00\n"), "\x00")
+
+	// if the first token is a status then the output contains the status of each file
+	if len(tokens) < 2 || !statusPattern.MatchString(tokens[0]) {
+		for _, token := range tokens {
+			if strings.TrimSpace(token) != "" {
+				files = append(files, models.ChangedFile{Status: models.ChangeModified, Path: token})
+			}
+		}
+
+		return files
+	}
+
+	for i := 0; i+1 < len(tokens); i += 2 {
+		status := strings.TrimLeft(tokens[i], "\n")
+		file := models.ChangedFile{Status: status[:1], Path: tokens[i+1]}
+
+		// renames and copies are followed by the old and then the new path
+		if (file.Status == models.ChangeRenamed || file.Status == models.ChangeCopied) && i+2 < len(tokens) {
+			file.OldPath = tokens[i+1]
+			file.Path = tokens[i+2]
+			i++
+		}
+
+		files = append(files, file)
+	}
+
+	return files
+}
+
+// normaliseFiles ensures that all of the paths are repo relative and use forward slashes
+func normaliseFiles(files []models.ChangedFile) []models.ChangedFile {
+
+	for i := range files {
+		files[i].Path = NormalisePath(files[i].Path)
+		files[i].OldPath = NormalisePath(files[i].OldPath)
+	}
+
+	return files
+}
+
+// NormalisePath converts a path so that it uses forward slashes and does not start with ./
+func NormalisePath(value string) string {
+
+	value = strings.TrimSpace(strings.ReplaceAll(value, "\\", "/"))
+	if value == "" {
+		return value
+	}
+
+	return path.Clean(value)
+}
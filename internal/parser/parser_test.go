@@ -0,0 +1,161 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/amido/mrbuild/internal/config"
+	"github.com/amido/mrbuild/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+
+	// create a list of tests to carry out
+	testCases := []struct {
+		name     string
+		format   string
+		data     string
+		expected []models.ChangedFile
+	}{
+		{
+			"lines",
+			config.InputFormatLines,
+			"src/api/main.go\r\n\n./src/web/index.js\nsrc\\infra\\main.tf\n",
+			[]models.ChangedFile{
+				{Status: "M", Path: "src/api/main.go"},
+				{Status: "M", Path: "src/web/index.js"},
+				{Status: "M", Path: "src/infra/main.tf"},
+			},
+		},
+		{
+			"lines with status",
+			config.InputFormatLines,
+			"A\tsrc/api/new.go\nR100\tsrc/old/a.go\tsrc/new/a.go\n",
+			[]models.ChangedFile{
+				{Status: "A", Path: "src/api/new.go"},
+				{Status: "R", Path: "src/new/a.go", OldPath: "src/old/a.go"},
+			},
+		},
+		{
+			"nul name only",
+			config.InputFormatNul,
+			"src/api/main.go\x00src/web/index.js\x00",
+			[]models.ChangedFile{
+				{Status: "M", Path: "src/api/main.go"},
+				{Status: "M", Path: "src/web/index.js"},
+			},
+		},
+		{
+			"nul name status",
+			config.InputFormatNul,
+			"M\x00src/api/main.go\x00R087\x00src/old/a.go\x00src/new/a.go\x00D\x00src/gone.tf\x00",
+			[]models.ChangedFile{
+				{Status: "M", Path: "src/api/main.go"},
+				{Status: "R", Path: "src/new/a.go", OldPath: "src/old/a.go"},
+				{Status: "D", Path: "src/gone.tf"},
+			},
+		},
+		{
+			"porcelain v1",
+			config.InputFormatPorcelain,
+			" M src/api/main.go\nA  src/api/new.go\nR  src/old/a.go -> src/new/a.go\n?? \"src/web/caf\\303\\251.js\"\n!! bin/out\n",
+			[]models.ChangedFile{
+				{Status: "M", Path: "src/api/main.go"},
+				{Status: "A", Path: "src/api/new.go"},
+				{Status: "R", Path: "src/new/a.go", OldPath: "src/old/a.go"},
+				{Status: "A", Path: "src/web/café.js"},
+			},
+		},
+		{
+			"porcelain v1 nul",
+			config.InputFormatPorcelain,
+			" D src/api/main.go\x00R  src/new/a.go\x00src/old/a.go\x00",
+			[]models.ChangedFile{
+				{Status: "D", Path: "src/api/main.go"},
+				{Status: "R", Path: "src/new/a.go", OldPath: "src/old/a.go"},
+			},
+		},
+		{
+			"porcelain v2",
+			config.InputFormatPorcelainV2,
+			"# branch.oid abc\n" +
+				"1 .M N... 100644 100644 100644 aaa aaa src/api/main.go\n" +
+				"2 R. N... 100644 100644 100644 bbb bbb R100 src/new/a.go\tsrc/old/a.go\n" +
+				"u UU N... 100644 100644 100644 100644 ccc ddd eee src/conflict.go\n" +
+				"? src/web/untracked.js\n" +
+				"! bin/out\n",
+			[]models.ChangedFile{
+				{Status: "M", Path: "src/api/main.go"},
+				{Status: "R", Path: "src/new/a.go", OldPath: "src/old/a.go"},
+				{Status: "M", Path: "src/conflict.go"},
+				{Status: "A", Path: "src/web/untracked.js"},
+			},
+		},
+		{
+			"json",
+			config.InputFormatJSON,
+			`["src/api/main.go", "src\\web\\index.js"]`,
+			[]models.ChangedFile{
+				{Status: "M", Path: "src/api/main.go"},
+				{Status: "M", Path: "src/web/index.js"},
+			},
+		},
+		{
+			"github paginated",
+			config.InputFormatGitHub,
+			`[{"filename": "src/api/main.go", "status": "modified"}, {"filename": "src/api/new.go", "status": "added"}]
+			[{"filename": "src/new/a.go", "status": "renamed", "previous_filename": "src/old/a.go"}, {"filename": "src/gone.tf", "status": "removed"}]`,
+			[]models.ChangedFile{
+				{Status: "M", Path: "src/api/main.go"},
+				{Status: "A", Path: "src/api/new.go"},
+				{Status: "R", Path: "src/new/a.go", OldPath: "src/old/a.go"},
+				{Status: "D", Path: "src/gone.tf"},
+			},
+		},
+		{
+			"gitlab",
+			config.InputFormatGitLab,
+			`{"iid": 1, "changes": [
+				{"old_path": "src/api/main.go", "new_path": "src/api/main.go"},
+				{"old_path": "src/api/new.go", "new_path": "src/api/new.go", "new_file": true},
+				{"old_path": "src/old/a.go", "new_path": "src/new/a.go", "renamed_file": true},
+				{"old_path": "src/gone.tf", "new_path": "src/gone.tf", "deleted_file": true}
+			]}`,
+			[]models.ChangedFile{
+				{Status: "M", Path: "src/api/main.go"},
+				{Status: "A", Path: "src/api/new.go"},
+				{Status: "R", Path: "src/new/a.go", OldPath: "src/old/a.go"},
+				{Status: "D", Path: "src/gone.tf"},
+			},
+		},
+		{
+			"gitlab diffs",
+			config.InputFormatGitLab,
+			`[{"old_path": "src/api/main.go", "new_path": "src/api/main.go"}]`,
+			[]models.ChangedFile{
+				{Status: "M", Path: "src/api/main.go"},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			files, err := Parse(testCase.format, []byte(testCase.data))
+
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expected, files)
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+
+	_, err := Parse("yaml", []byte("a"))
+	assert.Error(t, err, "Unknown formats should return an error")
+
+	_, err = Parse(config.InputFormatJSON, []byte(`{"not": "an array"}`))
+	assert.Error(t, err)
+
+	_, err = Parse(config.InputFormatGitHub, []byte(`[{"filename": `))
+	assert.Error(t, err)
+}
@@ -0,0 +1,198 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/amido/mrbuild/internal/models"
+)
+
+// ParsePorcelain converts the output of `git status --porcelain=v1` into a slice of changed files
+// Both the newline and the NUL (-z) delimited output is supported
+func ParsePorcelain(data string) ([]models.ChangedFile, error) {
+
+	var files []models.ChangedFile
+
+	nul := strings.Contains(data, "\x00")
+	entries := splitEntries(data, nul)
+
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+
+		status := porcelainStatus(entry[0], entry[1])
+		if status == "" {
+			continue
+		}
+
+		file := models.ChangedFile{Status: status, Path: entry[3:]}
+
+		if status == models.ChangeRenamed || status == models.ChangeCopied {
+			if nul {
+
+				// with -z the old path is the next entry
+				if i+1 < len(entries) {
+					file.OldPath = entries[i+1]
+					i++
+				}
+			} else if parts := strings.SplitN(file.Path, " -> ", 2); len(parts) == 2 {
+				file.OldPath = parts[0]
+				file.Path = parts[1]
+			}
+		}
+
+		var err error
+		if !nul {
+			if file.Path, err = unquote(file.Path); err != nil {
+				return nil, err
+			}
+
+			if file.OldPath, err = unquote(file.OldPath); err != nil {
+				return nil, err
+			}
+		}
+
+		files = append(files, file)
+	}
+
+	return files, nil
+}
+
+// ParsePorcelainV2 converts the output of `git status --porcelain=v2` into a slice of changed files
+// Both the newline and the NUL (-z) delimited output is supported
+func ParsePorcelainV2(data string) ([]models.ChangedFile, error) {
+
+	var files []models.ChangedFile
+
+	nul := strings.Contains(data, "\x00")
+	entries := splitEntries(data, nul)
+
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+
+		var file models.ChangedFile
+		var fields []string
+
+		switch {
+		case strings.HasPrefix(entry, "? "):
+			file = models.ChangedFile{Status: models.ChangeAdded, Path: entry[2:]}
+
+		case strings.HasPrefix(entry, "1 "):
+			if fields = strings.SplitN(entry, " ", 9); len(fields) < 9 {
+				return nil, fmt.Errorf("unable to parse porcelain v2 entry: %s", entry)
+			}
+
+			file = models.ChangedFile{Status: porcelainStatus(fields[1][0], fields[1][1]), Path: fields[8]}
+
+		case strings.HasPrefix(entry, "2 "):
+			if fields = strings.SplitN(entry, " ", 10); len(fields) < 10 {
+				return nil, fmt.Errorf("unable to parse porcelain v2 entry: %s", entry)
+			}
+
+			file = models.ChangedFile{Status: porcelainStatus(fields[1][0], fields[1][1]), Path: fields[9]}
+
+			// the old path is separated by a tab, or is the next entry when using -z
+			if nul {
+				if i+1 < len(entries) {
+					file.OldPath = entries[i+1]
+					i++
+				}
+			} else if parts := strings.SplitN(file.Path, "\t", 2); len(parts) == 2 {
+				file.Path = parts[0]
+				file.OldPath = parts[1]
+			}
+
+		case strings.HasPrefix(entry, "u "):
+			if fields = strings.SplitN(entry, " ", 11); len(fields) < 11 {
+				return nil, fmt.Errorf("unable to parse porcelain v2 entry: %s", entry)
+			}
+
+			file = models.ChangedFile{Status: models.ChangeModified, Path: fields[10]}
+
+		default:
+
+			// headers and ignored files are not changes
+			continue
+		}
+
+		var err error
+		if !nul {
+			if file.Path, err = unquote(file.Path); err != nil {
+				return nil, err
+			}
+
+			if file.OldPath, err = unquote(file.OldPath); err != nil {
+				return nil, err
+			}
+		}
+
+		files = append(files, file)
+	}
+
+	return files, nil
+}
+
+// porcelainStatus converts the index and working tree status codes from `git status`
+// into the status of the change. An empty string is returned for ignored files
+func porcelainStatus(x byte, y byte) string {
+
+	if x == '?' {
+		return models.ChangeAdded
+	}
+
+	if x == '!' {
+		return ""
+	}
+
+	// the index status takes precedence over the working tree
+	for _, code := range []byte{x, y} {
+		switch code {
+		case 'A', 'D', 'R', 'C':
+			return string(code)
+		}
+	}
+
+	return models.ChangeModified
+}
+
+// splitEntries splits the data into the entries from the status command
+func splitEntries(data string, nul bool) []string {
+
+	var entries []string
+	separator := "\n"
+
+	if nul {
+		separator = "\x00"
+	}
+
+	for _, entry := range strings.Split(data, separator) {
+		entry = strings.TrimRight(entry, "\r")
+		if nul {
+			entry = strings.TrimLeft(entry, "\n")
+		}
+
+		if entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+// unquote removes the C-style quoting that git applies to paths that contain special characters
+func unquote(value string) (string, error) {
+
+	if !strings.HasPrefix(value, `"`) {
+		return value, nil
+	}
+
+	result, err := strconv.Unquote(value)
+	if err != nil {
+		return "", fmt.Errorf("unable to unquote path %s: %s", value, err.Error())
+	}
+
+	return result, nil
+}
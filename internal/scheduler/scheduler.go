@@ -0,0 +1,215 @@
+package scheduler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/amido/mrbuild/internal/models"
+	"github.com/gammazero/workerpool"
+)
+
+const (
+	// StatusSucceeded states that the command of the project ran successfully
+	StatusSucceeded = "succeeded"
+
+	// StatusFailed states that the command of the project returned an error
+	StatusFailed = "failed"
+
+	// StatusSkipped states that the project was not run as one of its prerequisites failed
+	StatusSkipped = "skipped"
+)
+
+// Result holds the outcome of running the command of a project
+type Result struct {
+	Name   string
+	Status string
+	Reason string // Reason that the project was skipped, e.g. skipped because api failed
+	Err    error
+}
+
+// Scheduler runs the builds of the projects in the order of a directed acyclic graph
+// A project is a prerequisite of another if it is in a lower order tier, or if the other
+// project depends on it. Projects that do not depend on each other are run concurrently
+type Scheduler struct {
+	nodes []*node
+}
+
+// node is a single build in the graph
+type node struct {
+	spawn      models.SpawnBuild
+	needs      []int // indexes of the prerequisites of the build
+	dependents []int // indexes of the builds that have this build as a prerequisite
+	result     *Result
+}
+
+// completion is sent by a worker when the command of a build has finished
+type completion struct {
+	index int
+	err   error
+}
+
+// New builds the graph of the builds. The order tiers are applied first, so every build
+// in a tier needs every build in the lower tiers, and then the declared dependencies
+// An error is returned if a build depends on a build in a higher tier
+func New(spawns []models.SpawnBuild) (*Scheduler, error) {
+
+	s := &Scheduler{}
+	index := make(map[string]int)
+
+	for i, spawn := range spawns {
+		s.nodes = append(s.nodes, &node{spawn: spawn})
+		index[spawn.Name] = i
+	}
+
+	for i, n := range s.nodes {
+		needs := make(map[int]bool)
+
+		for j, other := range s.nodes {
+			if other.spawn.Order < n.spawn.Order {
+				needs[j] = true
+			}
+		}
+
+		for _, name := range n.spawn.DependsOn {
+			j, ok := index[name]
+			if !ok {
+				continue
+			}
+
+			// this is also reported when the graph of the projects is built, so that it does
+			// not depend on which projects have been affected
+			if s.nodes[j].spawn.Order > n.spawn.Order {
+				return nil, fmt.Errorf("project %s depends on %s, which has a higher order", n.spawn.Name, name)
+			}
+
+			needs[j] = true
+		}
+
+		for j := range needs {
+			n.needs = append(n.needs, j)
+		}
+		sort.Ints(n.needs)
+
+		for _, j := range n.needs {
+			s.nodes[j].dependents = append(s.nodes[j].dependents, i)
+		}
+	}
+
+	if cycle := s.findCycle(); len(cycle) > 0 {
+		return nil, fmt.Errorf("unable to schedule projects as they depend on each other: %s", strings.Join(cycle, ", "))
+	}
+
+	return s, nil
+}
+
+// Run runs each build on the pool once all of its prerequisites have succeeded, and
+// waits for all of them to finish. If a build fails, the builds that need it, directly
+// or transitively, are skipped. The results are returned in the order of the builds
+func (s *Scheduler) Run(pool *workerpool.WorkerPool, run func(spawn models.SpawnBuild) error) []Result {
+
+	done := make(chan completion)
+	remaining := make([]int, len(s.nodes))
+	pending := len(s.nodes)
+
+	submit := func(i int) {
+		spawn := s.nodes[i].spawn
+		pool.Submit(func() {
+			done <- completion{index: i, err: run(spawn)}
+		})
+	}
+
+	for i, n := range s.nodes {
+		remaining[i] = len(n.needs)
+		if remaining[i] == 0 {
+			submit(i)
+		}
+	}
+
+	for pending > 0 {
+		c := <-done
+		n := s.nodes[c.index]
+		pending--
+
+		if c.err != nil {
+			n.result = &Result{Name: n.spawn.Name, Status: StatusFailed, Err: c.err}
+			pending -= s.skip(c.index, n.spawn.Name)
+			continue
+		}
+
+		n.result = &Result{Name: n.spawn.Name, Status: StatusSucceeded}
+
+		for _, j := range n.dependents {
+			remaining[j]--
+			if remaining[j] == 0 && s.nodes[j].result == nil {
+				submit(j)
+			}
+		}
+	}
+
+	results := make([]Result, len(s.nodes))
+	for i, n := range s.nodes {
+		results[i] = *n.result
+	}
+
+	return results
+}
+
+// skip marks every build that needs the failed build, directly or transitively, as
+// skipped and returns the number of builds that have been skipped
+func (s *Scheduler) skip(index int, failed string) int {
+
+	count := 0
+
+	for _, j := range s.nodes[index].dependents {
+		if s.nodes[j].result != nil {
+			continue
+		}
+
+		s.nodes[j].result = &Result{
+			Name:   s.nodes[j].spawn.Name,
+			Status: StatusSkipped,
+			Reason: fmt.Sprintf("skipped because %s failed", failed),
+		}
+
+		count += 1 + s.skip(j, failed)
+	}
+
+	return count
+}
+
+// findCycle returns the names of the builds that can never be started because they
+// need each other, or nil if every build can be run
+func (s *Scheduler) findCycle() []string {
+
+	remaining := make([]int, len(s.nodes))
+	var queue []int
+
+	for i, n := range s.nodes {
+		remaining[i] = len(n.needs)
+		if remaining[i] == 0 {
+			queue = append(queue, i)
+		}
+	}
+
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+
+		for _, j := range s.nodes[i].dependents {
+			remaining[j]--
+			if remaining[j] == 0 {
+				queue = append(queue, j)
+			}
+		}
+	}
+
+	var cycle []string
+	for i, n := range s.nodes {
+		if remaining[i] > 0 {
+			cycle = append(cycle, n.spawn.Name)
+		}
+	}
+
+	return cycle
+}
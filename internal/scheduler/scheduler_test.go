@@ -0,0 +1,146 @@
+package scheduler
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/amido/mrbuild/internal/models"
+	"github.com/gammazero/workerpool"
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder records when each build starts and finishes
+type recorder struct {
+	lock   sync.Mutex
+	events []string
+}
+
+func (r *recorder) add(event string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.events = append(r.events, event)
+}
+
+// index returns the position of the event, or -1 if it did not happen
+func (r *recorder) index(event string) int {
+	for i, item := range r.events {
+		if item == event {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func TestNew(t *testing.T) {
+
+	tables := []struct {
+		name   string
+		spawns []models.SpawnBuild
+		err    string
+	}{
+		{
+			"order and dependencies",
+			[]models.SpawnBuild{{Name: "infra"}, {Name: "lib", Order: 1}, {Name: "api", Order: 1, DependsOn: []string{"lib"}}},
+			"",
+		},
+		{
+			"dependency that is not being built",
+			[]models.SpawnBuild{{Name: "api", DependsOn: []string{"lib"}}},
+			"",
+		},
+		{
+			"dependency in a higher tier",
+			[]models.SpawnBuild{{Name: "api", DependsOn: []string{"lib"}}, {Name: "lib", Order: 1}},
+			"project api depends on lib, which has a higher order",
+		},
+		{
+			"cycle",
+			[]models.SpawnBuild{{Name: "api", DependsOn: []string{"lib"}}, {Name: "lib", DependsOn: []string{"api"}}, {Name: "web"}},
+			"unable to schedule projects as they depend on each other: api, lib",
+		},
+	}
+
+	for _, table := range tables {
+		_, err := New(table.spawns)
+
+		if table.err == "" {
+			assert.NoError(t, err, table.name)
+		} else {
+			assert.EqualError(t, err, table.err, table.name)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+
+	spawns := []models.SpawnBuild{
+		{Name: "infra"},
+		{Name: "lib"},
+		{Name: "api", DependsOn: []string{"lib"}},
+		{Name: "web", DependsOn: []string{"api", "lib"}},
+		{Name: "docs"},
+		{Name: "deploy", Order: 1},
+	}
+
+	s, err := New(spawns)
+	assert.NoError(t, err)
+
+	r := &recorder{}
+	results := s.Run(workerpool.New(3), func(spawn models.SpawnBuild) error {
+		r.add("start " + spawn.Name)
+		time.Sleep(10 * time.Millisecond)
+		r.add("end " + spawn.Name)
+		return nil
+	})
+
+	for _, result := range results {
+		assert.Equal(t, StatusSucceeded, result.Status, result.Name)
+	}
+
+	assert.Less(t, r.index("end lib"), r.index("start api"), "api should start after lib")
+	assert.Less(t, r.index("end api"), r.index("start web"), "web should start after api")
+
+	for _, name := range []string{"infra", "lib", "api", "web", "docs"} {
+		assert.Less(t, r.index("end "+name), r.index("start deploy"), "deploy should start after the lower tier %s", name)
+	}
+
+	assert.Less(t, r.index("start docs"), r.index("end infra"), "independent projects should run concurrently")
+}
+
+func TestRunFailure(t *testing.T) {
+
+	spawns := []models.SpawnBuild{
+		{Name: "lib"},
+		{Name: "api", DependsOn: []string{"lib"}},
+		{Name: "web", DependsOn: []string{"api"}},
+		{Name: "docs"},
+		{Name: "deploy", Order: 1},
+	}
+
+	s, err := New(spawns)
+	assert.NoError(t, err)
+
+	r := &recorder{}
+	results := s.Run(workerpool.New(2), func(spawn models.SpawnBuild) error {
+		r.add("start " + spawn.Name)
+		if spawn.Name == "lib" {
+			return errors.New("build failed")
+		}
+		return nil
+	})
+
+	expected := []Result{
+		{Name: "lib", Status: StatusFailed, Err: errors.New("build failed")},
+		{Name: "api", Status: StatusSkipped, Reason: "skipped because lib failed"},
+		{Name: "web", Status: StatusSkipped, Reason: "skipped because lib failed"},
+		{Name: "docs", Status: StatusSucceeded},
+		{Name: "deploy", Status: StatusSkipped, Reason: "skipped because lib failed"},
+	}
+
+	assert.Equal(t, expected, results)
+	assert.Equal(t, -1, r.index("start api"), "A skipped project should not be run")
+}
@@ -0,0 +1,112 @@
+package snapshot
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// Hash creates a manifest of the hashes of all the files in the folders, which are relative
+// to the root directory. Folders that do not exist are skipped as the project may have been
+// removed. Git directories, and any of the paths to skip, are not included
+func Hash(root string, folders []string, skip ...string) (*Manifest, error) {
+	return hashMatching(root, folders, nil, skip...)
+}
+
+// hashMatching creates a manifest of the hashes of the files in the folders in the same way as
+// Hash, but if the match function has been set only the files that it matches are hashed
+func hashMatching(root string, folders []string, match func(string) bool, skip ...string) (*Manifest, error) {
+
+	manifest := NewManifest()
+
+	excluded := make(map[string]bool)
+	for _, item := range skip {
+		excluded[path.Clean(filepath.ToSlash(item))] = true
+	}
+
+	for _, folder := range folders {
+
+		start := filepath.Join(root, filepath.FromSlash(folder))
+
+		if _, err := os.Stat(start); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+
+		err := filepath.WalkDir(start, func(full string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			rel, err := filepath.Rel(root, full)
+			if err != nil {
+				return err
+			}
+			rel = filepath.ToSlash(rel)
+
+			if entry.IsDir() {
+				if entry.Name() == ".git" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			// a .git file is used by submodules and worktrees to point to the repository
+			if entry.Name() == ".git" || excluded[rel] || (match != nil && !match(rel)) {
+				return nil
+			}
+
+			// the same file may be in more than one project
+			if _, ok := manifest.Files[rel]; ok {
+				return nil
+			}
+
+			hash, err := hashFile(full, entry)
+			if err != nil {
+				return err
+			}
+
+			manifest.Files[rel] = hash
+
+			return nil
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return manifest, nil
+}
+
+// hashFile returns the SHA256 hash of the content of the file
+// For a symbolic link the target of the link is hashed
+func hashFile(full string, entry fs.DirEntry) (string, error) {
+
+	hash := sha256.New()
+
+	if entry.Type()&fs.ModeSymlink != 0 {
+		target, err := os.Readlink(full)
+		if err != nil {
+			return "", err
+		}
+
+		hash.Write([]byte(filepath.ToSlash(target)))
+	} else {
+		file, err := os.Open(full)
+		if err != nil {
+			return "", err
+		}
+		defer file.Close()
+
+		if _, err = io.Copy(hash, file); err != nil {
+			return "", err
+		}
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
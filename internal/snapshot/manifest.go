@@ -0,0 +1,100 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/amido/mrbuild/internal/models"
+)
+
+// ManifestVersion is the version of the format of the manifest
+const ManifestVersion = 1
+
+// Manifest holds the hash of the content of each file in the projects
+// The paths are relative to the working directory and use forward slashes
+type Manifest struct {
+	Version int               `json:"version"`
+	Files   map[string]string `json:"files"`
+}
+
+// NewManifest allocates an empty manifest
+func NewManifest() *Manifest {
+	return &Manifest{
+		Version: ManifestVersion,
+		Files:   make(map[string]string),
+	}
+}
+
+// Load reads the manifest from the specified file
+func Load(path string) (*Manifest, error) {
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := NewManifest()
+	if err = json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("unable to read snapshot manifest '%s': %s", path, err.Error())
+	}
+
+	if manifest.Version != ManifestVersion {
+		return nil, fmt.Errorf("unsupported snapshot manifest version %d in '%s'", manifest.Version, path)
+	}
+
+	if manifest.Files == nil {
+		manifest.Files = make(map[string]string)
+	}
+
+	return manifest, nil
+}
+
+// Save writes the manifest to the specified file, creating the directory if required
+func (m *Manifest) Save(path string) error {
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err = os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Compare returns the files that have changed since the previous manifest was saved
+// Files that have the same hash in both manifests are not included
+func (m *Manifest) Compare(previous *Manifest) []models.ChangedFile {
+
+	var files []models.ChangedFile
+
+	for path, hash := range m.Files {
+		old, ok := previous.Files[path]
+
+		switch {
+		case !ok:
+			files = append(files, models.ChangedFile{Status: models.ChangeAdded, Path: path})
+		case old != hash:
+			files = append(files, models.ChangedFile{Status: models.ChangeModified, Path: path})
+		}
+	}
+
+	for path := range previous.Files {
+		if _, ok := m.Files[path]; !ok {
+			files = append(files, models.ChangedFile{Status: models.ChangeDeleted, Path: path})
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+
+	return files
+}
@@ -0,0 +1,88 @@
+package snapshot
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/amido/mrbuild/internal/config"
+	"github.com/amido/mrbuild/internal/match"
+)
+
+// Take hashes the files in the folders of all of the projects in the configuration, in
+// the directories of their inputs and the files that match the global triggers
+// The manifest file itself is not included, in case it is saved within a project
+func Take(conf *config.Config) (*Manifest, error) {
+
+	var folders []string
+
+	root := conf.Input.Directory.WorkingDir
+
+	for _, project := range conf.Input.Projects {
+		folders = append(folders, project.Folder)
+
+		for _, input := range project.Inputs {
+			if base := match.Base(input); base != "" {
+				folders = append(folders, base)
+			}
+		}
+	}
+
+	var skip []string
+	if rel, err := filepath.Rel(root, GetManifestPath(conf)); err == nil {
+		skip = append(skip, rel)
+	}
+
+	manifest, err := Hash(root, folders, skip...)
+	if err != nil || len(conf.Input.GlobalTriggers) == 0 {
+		return manifest, err
+	}
+
+	// only the files that match a global trigger are hashed, as a trigger such as
+	// Directory.Build.props can match a file in any directory of the repository
+	var triggers []string
+	seen := make(map[string]bool)
+
+	for _, pattern := range conf.Input.GlobalTriggers {
+		if base := getTriggerBase(pattern); !seen[base] {
+			seen[base] = true
+			triggers = append(triggers, base)
+		}
+	}
+
+	triggered, err := hashMatching(root, triggers, conf.Input.IsGlobalTrigger, skip...)
+	if err != nil {
+		return nil, err
+	}
+
+	for path, hash := range triggered.Files {
+		manifest.Files[path] = hash
+	}
+
+	return manifest, nil
+}
+
+// getTriggerBase returns the directory that contains all of the files that the global
+// trigger can match. A pattern without a slash, other than at the end, matches at any
+// depth so the whole of the repository is searched
+func getTriggerBase(pattern string) string {
+
+	pattern = strings.TrimSuffix(strings.TrimSpace(pattern), "/")
+	if !strings.Contains(pattern, "/") {
+		return "."
+	}
+
+	return match.Base(pattern)
+}
+
+// GetManifestPath returns the path to the manifest file
+// A relative path is relative to the working directory
+func GetManifestPath(conf *config.Config) string {
+
+	path := conf.Input.Snapshot.Manifest
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(conf.Input.Directory.WorkingDir, path)
+	}
+
+	return path
+}
@@ -0,0 +1,121 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/amido/mrbuild/internal/config"
+	"github.com/amido/mrbuild/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFile(t *testing.T, root string, path string, content string) {
+	full := filepath.Join(root, filepath.FromSlash(path))
+
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHash(t *testing.T) {
+	root := t.TempDir()
+
+	writeFile(t, root, "src/api/main.go", "package main")
+	writeFile(t, root, "src/api/.git/HEAD", "ref: refs/heads/main")
+	writeFile(t, root, "src/web/app.js", "console.log('web')")
+	writeFile(t, root, "src/web/snapshot.json", "{}")
+	writeFile(t, root, "docs/README.md", "# Docs")
+
+	manifest, err := Hash(root, []string{"src/api", "src/web", "src/removed"}, "src/web/snapshot.json")
+	assert.NoError(t, err)
+
+	paths := make([]string, 0, len(manifest.Files))
+	for path := range manifest.Files {
+		paths = append(paths, path)
+	}
+
+	assert.ElementsMatch(t, []string{"src/api/main.go", "src/web/app.js"}, paths, "Only the files in the folders should be hashed")
+	assert.Len(t, manifest.Files["src/api/main.go"], 64, "The SHA256 hash should be stored")
+}
+
+func TestCompare(t *testing.T) {
+
+	previous := NewManifest()
+	previous.Files = map[string]string{
+		"src/api/main.go":   "aaa",
+		"src/api/util.go":   "bbb",
+		"src/web/app.js":    "ccc",
+		"src/infra/main.tf": "ddd",
+	}
+
+	current := NewManifest()
+	current.Files = map[string]string{
+		"src/api/main.go": "aaa",
+		"src/api/util.go": "eee",
+		"src/web/app.js":  "ccc",
+		"src/web/new.js":  "fff",
+	}
+
+	expected := []models.ChangedFile{
+		{Status: models.ChangeModified, Path: "src/api/util.go"},
+		{Status: models.ChangeDeleted, Path: "src/infra/main.tf"},
+		{Status: models.ChangeAdded, Path: "src/web/new.js"},
+	}
+
+	assert.Equal(t, expected, current.Compare(previous))
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out", "snapshot.json")
+
+	manifest := NewManifest()
+	manifest.Files["src/api/main.go"] = "aaa"
+
+	assert.NoError(t, manifest.Save(path))
+
+	loaded, err := Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, manifest, loaded)
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.json"))
+	assert.ErrorIs(t, err, os.ErrNotExist, "A missing manifest should be reported as not existing")
+
+	writeFile(t, filepath.Dir(path), "bad.json", `{"version": 99}`)
+	_, err = Load(filepath.Join(filepath.Dir(path), "bad.json"))
+	assert.Error(t, err, "An unknown version should not be loaded")
+}
+
+func TestTakeGlobalTriggers(t *testing.T) {
+	root := t.TempDir()
+
+	writeFile(t, root, "src/api/main.go", "package main")
+	writeFile(t, root, "src/api/Directory.Build.props", "<Project />")
+	writeFile(t, root, "build/version.sh", "echo 1.0")
+	writeFile(t, root, "eirctl.yaml", "tasks:")
+	writeFile(t, root, "docs/README.md", "# Docs")
+	writeFile(t, root, "docs/Directory.Build.props", "<Project />")
+
+	conf := &config.Config{}
+	conf.Input.Directory.WorkingDir = root
+	conf.Input.Snapshot.Manifest = ".mrbuild-snapshot.json"
+	conf.Input.GlobalTriggers = []string{"build/", "/eirctl.yaml", "Directory.Build.props"}
+	conf.Input.Projects = []config.Project{
+		{Name: "api", Folder: "src/api"},
+	}
+
+	manifest, err := Take(conf)
+	assert.NoError(t, err)
+
+	paths := make([]string, 0, len(manifest.Files))
+	for path := range manifest.Files {
+		paths = append(paths, path)
+	}
+
+	expected := []string{"src/api/main.go", "src/api/Directory.Build.props", "build/version.sh", "eirctl.yaml", "docs/Directory.Build.props"}
+	assert.ElementsMatch(t, expected, paths, "The files that match the global triggers should be hashed")
+}
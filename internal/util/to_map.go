@@ -0,0 +1,68 @@
+package util
+
+import (
+	"reflect"
+	"strings"
+)
+
+// ToMap converts a struct into maps, using the mapstructure tags of the fields as the keys,
+// so that it can be written out in the same form as the configuration file
+// Fields that have not been set, unexported fields and fields tagged with - are omitted
+func ToMap(value interface{}) interface{} {
+	return toMap(reflect.ValueOf(value))
+}
+
+func toMap(value reflect.Value) interface{} {
+
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if value.IsNil() {
+			return nil
+		}
+		return toMap(value.Elem())
+
+	case reflect.Struct:
+		result := make(map[string]interface{})
+
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			if !field.IsExported() || value.Field(i).IsZero() {
+				continue
+			}
+
+			name := strings.Split(field.Tag.Get("mapstructure"), ",")[0]
+			if name == "-" {
+				continue
+			}
+
+			if name == "" {
+				name = strings.ToLower(field.Name)
+			}
+
+			result[name] = toMap(value.Field(i))
+		}
+
+		return result
+
+	case reflect.Slice, reflect.Array:
+		result := make([]interface{}, value.Len())
+
+		for i := range result {
+			result[i] = toMap(value.Index(i))
+		}
+
+		return result
+
+	case reflect.Map:
+		result := make(map[string]interface{})
+
+		iter := value.MapRange()
+		for iter.Next() {
+			result[iter.Key().String()] = toMap(iter.Value())
+		}
+
+		return result
+	}
+
+	return value.Interface()
+}
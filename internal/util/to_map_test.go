@@ -0,0 +1,46 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToMap(t *testing.T) {
+
+	type build struct {
+		Cmd string `mapstructure:"cmd"`
+	}
+
+	type project struct {
+		Name     string            `mapstructure:"name"`
+		OnDelete string            `mapstructure:"on_delete"`
+		Patterns []string          `mapstructure:"patterns"`
+		Env      map[string]string `mapstructure:"env"`
+		Build    build             `mapstructure:"build"`
+		Order    int
+		Internal string `mapstructure:"-"`
+		private  string
+	}
+
+	value := project{
+		Name:     "api",
+		Patterns: []string{".*"},
+		Env:      map[string]string{"stage": "dev"},
+		Build:    build{Cmd: "make"},
+		Order:    1,
+		Internal: "skip",
+		private:  "skip",
+	}
+
+	expected := map[string]interface{}{
+		"name":     "api",
+		"patterns": []interface{}{".*"},
+		"env":      map[string]interface{}{"stage": "dev"},
+		"build":    map[string]interface{}{"cmd": "make"},
+		"order":    1,
+	}
+
+	assert.Equal(t, expected, ToMap(value))
+	assert.Equal(t, expected, ToMap(&value))
+}